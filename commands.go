@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"thedefiant.io/analytics/models"
+	repository "thedefiant.io/analytics/repositories"
+	"thedefiant.io/analytics/utils"
+)
+
+// runBackfillCommand handles `analytics backfill -from 2024-01-01 -to 2024-03-31 [-chunk 7]`
+// and `analytics backfill -resume <id>`
+func runBackfillCommand(postRepo *repository.PostRepository, args []string) error {
+	fs := flag.NewFlagSet("backfill", flag.ContinueOnError)
+	from := fs.String("from", "", "first date to backfill (YYYY-MM-DD)")
+	to := fs.String("to", utils.GetYesterdayDate(), "last date to backfill (YYYY-MM-DD)")
+	chunkDays := fs.Int("chunk", 1, "number of days fetched per GA request")
+	resume := fs.Uint("resume", 0, "ID of an interrupted backfill job to resume")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var job *models.BackfillJob
+	var err error
+	if *resume != 0 {
+		job, err = postRepo.GetBackfillJob(*resume)
+	} else {
+		if *from == "" {
+			return fmt.Errorf("-from is required")
+		}
+		job, err = postRepo.CreateBackfillJob(*from, *to, *chunkDays)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Running backfill %d from %s to %s\n", job.ID, utils.FormatDate(job.NextDate), utils.FormatDate(job.EndDate))
+	return postRepo.RunBackfill(job)
+}
+
+// runAuthorReportsCommand handles `analytics author-reports -from 2024-01 [-to 2024-06]`,
+// (re)building the monthly author reports of every month in the range
+func runAuthorReportsCommand(authorRepo *repository.AuthorRepository, args []string) error {
+	fs := flag.NewFlagSet("author-reports", flag.ContinueOnError)
+	from := fs.String("from", "", "first month to report (YYYY-MM)")
+	to := fs.String("to", time.Now().UTC().AddDate(0, -1, 0).Format("2006-01"), "last month to report (YYYY-MM)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *from == "" {
+		return fmt.Errorf("-from is required")
+	}
+
+	months, err := authorRepo.BackfillMonthlyAuthorViews(*from, *to)
+	fmt.Printf("Stored author reports for %d months\n", months)
+	return err
+}
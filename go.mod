@@ -2,7 +2,10 @@ module thedefiant.io/analytics
 
 go 1.23.1
 
-require golang.org/x/oauth2 v0.23.0
+require (
+	golang.org/x/oauth2 v0.23.0
+	golang.org/x/sync v0.8.0
+)
 
 require (
 	cloud.google.com/go/auth v0.9.3 // indirect
@@ -27,7 +30,6 @@ require (
 	go.opentelemetry.io/otel/trace v1.29.0 // indirect
 	golang.org/x/crypto v0.27.0 // indirect
 	golang.org/x/net v0.29.0 // indirect
-	golang.org/x/sync v0.8.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20240903143218-8af14fe29dc1 // indirect
 	google.golang.org/grpc v1.66.1 // indirect
 	google.golang.org/protobuf v1.34.2 // indirect
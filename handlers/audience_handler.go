@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	repository "thedefiant.io/analytics/repositories"
+)
+
+type AudienceHandler struct {
+	Repo *repository.AudienceRepository
+}
+
+func NewAudienceHandler(repo *repository.AudienceRepository) *AudienceHandler {
+	return &AudienceHandler{Repo: repo}
+}
+
+func (h *AudienceHandler) GetPostAudience(c *fiber.Ctx) error {
+	from, to, ok := parseDateRange(c)
+	if !ok {
+		return invalidDateRange(c)
+	}
+	audience, err := h.Repo.GetPostAudience(c.Params("id"), from, to)
+	return h.respond(c, audience, err)
+}
+
+func (h *AudienceHandler) GetAuthorAudience(c *fiber.Ctx) error {
+	from, to, ok := parseDateRange(c)
+	if !ok {
+		return invalidDateRange(c)
+	}
+	audience, err := h.Repo.GetAuthorAudience(c.Params("id"), from, to)
+	return h.respond(c, audience, err)
+}
+
+func (h *AudienceHandler) GetCategoryAudience(c *fiber.Ctx) error {
+	from, to, ok := parseDateRange(c)
+	if !ok {
+		return invalidDateRange(c)
+	}
+	audience, err := h.Repo.GetCategoryAudience(c.Params("category"), c.Query("subCategory"), from, to)
+	return h.respond(c, audience, err)
+}
+
+func (h *AudienceHandler) respond(c *fiber.Ctx, audience *repository.AudienceBreakdown, err error) error {
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Error fetching audience",
+			"error":   err.Error(),
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message": "Audience fetched successfully",
+		"data":    audience,
+	})
+}
@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	repository "thedefiant.io/analytics/repositories"
@@ -15,8 +17,23 @@ func NewAuthorHandler(repo *repository.AuthorRepository) *AuthorHandler {
 	return &AuthorHandler{Repo: repo}
 }
 
+// GetAuthors returns every author's views per period, summed from stored post data.
+// Query parameters: period (daily, weekly, monthly or rolling30, monthly by default) and
+// from and to, defaulting to the last 30 days.
 func (h *AuthorHandler) GetAuthors(c *fiber.Ctx) error {
-	authors, err := h.Repo.GetAuthorsFromDatabase()
+	from, to, ok := parseDateRange(c)
+	if !ok {
+		return invalidDateRange(c)
+	}
+	period := c.Query("period", repository.AuthorPeriodMonthly)
+
+	authors, err := h.Repo.GetAuthorRollups(period, from, to)
+	if errors.Is(err, repository.ErrInvalidAuthorPeriod) {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid period",
+			"error":   err.Error(),
+		})
+	}
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Error fetching authors",
@@ -26,6 +43,9 @@ func (h *AuthorHandler) GetAuthors(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{
 		"message": "Authors fetched successfully",
 		"data":    authors,
+		"period":  period,
+		"from":    from,
+		"to":      to,
 	})
 }
 
@@ -43,6 +63,9 @@ func (h *AuthorHandler) CreateAuthor(c *fiber.Ctx) error {
 	})
 }
 
+// GetAuthorByID returns an author's profile for the period given by the from and to query
+// dates (the last 30 days by default): their posts, monthly views, rank, category mix and
+// the change from the period before
 func (h *AuthorHandler) GetAuthorByID(c *fiber.Ctx) error {
 	id := c.Params("id")
 	if id == "" {
@@ -50,8 +73,17 @@ func (h *AuthorHandler) GetAuthorByID(c *fiber.Ctx) error {
 			"message": "Author ID cannot be empty",
 		})
 	}
+	from, to, ok := parseDateRange(c)
+	if !ok {
+		return invalidDateRange(c)
+	}
 
-	author, err := h.Repo.GetAuthorByID(id)
+	profile, err := h.Repo.GetAuthorProfile(id, from, to)
+	if errors.Is(err, repository.ErrAuthorNotFound) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"message": "Author not found",
+		})
+	}
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Error fetching author",
@@ -60,7 +92,54 @@ func (h *AuthorHandler) GetAuthorByID(c *fiber.Ctx) error {
 	}
 	return c.JSON(fiber.Map{
 		"message": "Author fetched successfully",
-		"data":    author,
+		"data":    profile,
 	})
 }
 
+
+// GetMonthlyReports returns the stored calendar-month author views between the from and
+// to months (YYYY-MM, the last 12 finished months by default), optionally for one author
+func (h *AuthorHandler) GetMonthlyReports(c *fiber.Ctx) error {
+	lastMonth := time.Now().UTC().AddDate(0, -1, 0)
+	from := c.Query("from", lastMonth.AddDate(0, -11, 0).Format("2006-01"))
+	to := c.Query("to", lastMonth.Format("2006-01"))
+
+	reports, err := h.Repo.GetMonthlyAuthorViews(from, to, c.Query("author"))
+	if errors.Is(err, repository.ErrInvalidMonth) {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid month parameter",
+			"error":   err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Error fetching author reports",
+			"error":   err.Error(),
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message": "Author reports fetched successfully",
+		"data":    reports,
+	})
+}
+
+// BackfillMonthlyReports (re)builds the monthly author reports between the from and to
+// months (YYYY-MM). Months that were already stored are replaced.
+func (h *AuthorHandler) BackfillMonthlyReports(c *fiber.Ctx) error {
+	months, err := h.Repo.BackfillMonthlyAuthorViews(c.Query("from"), c.Query("to"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, repository.ErrInvalidMonth) {
+			status = http.StatusBadRequest
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"message": "Error backfilling author reports",
+			"error":   err.Error(),
+			"months":  months,
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message": "Author reports backfilled successfully",
+		"months":  months,
+	})
+}
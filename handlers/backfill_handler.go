@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"thedefiant.io/analytics/models"
+	repository "thedefiant.io/analytics/repositories"
+	"thedefiant.io/analytics/utils"
+)
+
+type BackfillHandler struct {
+	Repo *repository.PostRepository
+}
+
+func NewBackfillHandler(repo *repository.PostRepository) *BackfillHandler {
+	return &BackfillHandler{Repo: repo}
+}
+
+// StartBackfill creates (or resumes) a backfill job and runs it in the background
+func (h *BackfillHandler) StartBackfill(c *fiber.Ctx) error {
+	chunkDays, err := strconv.Atoi(c.Query("chunk", "1"))
+	if err != nil || chunkDays <= 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid chunk parameter",
+			"error":   "Chunk must be a positive integer",
+		})
+	}
+
+	job, err := h.Repo.CreateBackfillJob(c.Query("from"), c.Query("to", utils.GetYesterdayDate()), chunkDays)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "Error creating backfill",
+			"error":   err.Error(),
+		})
+	}
+	return h.run(c, job)
+}
+
+// ResumeBackfill continues an interrupted or failed backfill job
+func (h *BackfillHandler) ResumeBackfill(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid backfill ID",
+		})
+	}
+
+	job, err := h.Repo.GetBackfillJob(uint(id))
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"message": "Backfill not found",
+			"error":   err.Error(),
+		})
+	}
+	return h.run(c, job)
+}
+
+func (h *BackfillHandler) GetBackfills(c *fiber.Ctx) error {
+	jobs, err := h.Repo.GetBackfillJobs()
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Error fetching backfills",
+			"error":   err.Error(),
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message": "Backfills fetched successfully",
+		"data":    jobs,
+	})
+}
+
+func (h *BackfillHandler) run(c *fiber.Ctx, job *models.BackfillJob) error {
+	if job.Status == models.BackfillStatusCompleted {
+		return c.JSON(fiber.Map{
+			"message": "Backfill already completed",
+			"data":    job,
+		})
+	}
+
+	// The background run updates its own copy of the job while this one is serialized
+	running := *job
+	err := h.Repo.StartBackfill(&running)
+	if errors.Is(err, repository.ErrBackfillRunning) {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{
+			"message": "Another backfill is already running",
+			"error":   err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Error starting backfill",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.Status(http.StatusAccepted).JSON(fiber.Map{
+		"message": "Backfill started",
+		"data":    job,
+	})
+}
@@ -114,7 +114,7 @@ func (h *BeehiivHandler) GetTopPerformingPosts(c *fiber.Ctx) error {
 
 // UpdatePostMetrics triggers a manual update of post metrics
 func (h *BeehiivHandler) UpdatePostMetrics(c *fiber.Ctx) error {
-	err := h.Repo.UpdatePostMetrics()
+	err := h.Repo.UpdatePostMetrics(c.UserContext())
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Error updating post metrics",
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	repository "thedefiant.io/analytics/repositories"
+)
+
+type CategoryHandler struct {
+	Repo *repository.PostRepository
+}
+
+func NewCategoryHandler(repo *repository.PostRepository) *CategoryHandler {
+	return &CategoryHandler{Repo: repo}
+}
+
+// GetCategories returns every main category's views, post counts and change from the
+// previous period, defaulting to the last 30 days. Pass groupBy=subCategory to break
+// them down by subcategory; posts without one are listed under "(none)".
+func (h *CategoryHandler) GetCategories(c *fiber.Ctx) error {
+	from, to, ok := parseDateRange(c)
+	if !ok {
+		return invalidDateRange(c)
+	}
+	groupBy := c.Query("groupBy", "mainCategory")
+	if groupBy != "mainCategory" && groupBy != "subCategory" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid groupBy parameter",
+			"error":   "groupBy must be mainCategory or subCategory",
+		})
+	}
+
+	categories, err := h.Repo.GetCategoryRollups(from, to, "", groupBy == "subCategory")
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Error fetching categories",
+			"error":   err.Error(),
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message": "Categories fetched successfully",
+		"data":    categories,
+	})
+}
+
+// GetCategory returns one main category's totals together with a breakdown of its
+// subcategories, defaulting to the last 30 days
+func (h *CategoryHandler) GetCategory(c *fiber.Ctx) error {
+	from, to, ok := parseDateRange(c)
+	if !ok {
+		return invalidDateRange(c)
+	}
+	category := c.Params("category")
+
+	totals, err := h.Repo.GetCategoryRollups(from, to, category, false)
+	if errors.Is(err, repository.ErrCategoryNotFound) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"message": "Category not found",
+		})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Error fetching category",
+			"error":   err.Error(),
+		})
+	}
+	subCategories, err := h.Repo.GetCategoryRollups(from, to, category, true)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Error fetching subcategories",
+			"error":   err.Error(),
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message":       "Category fetched successfully",
+		"data":          totals[0],
+		"subCategories": subCategories,
+	})
+}
@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"thedefiant.io/analytics/utils"
+)
+
+// parseDateRange reads from/to query parameters, defaulting to the last 30 days
+func parseDateRange(c *fiber.Ctx) (string, string, bool) {
+	from := c.Query("from", utils.GetDateNDaysAgo(30))
+	to := c.Query("to", utils.GetYesterdayDate())
+	return from, to, utils.IsValidDateRange(from, to)
+}
+
+func invalidDateRange(c *fiber.Ctx) error {
+	return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+		"message": "Invalid date range",
+		"error":   "from and to must be YYYY-MM-DD dates with from <= to",
+	})
+}
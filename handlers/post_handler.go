@@ -1,11 +1,14 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 	"thedefiant.io/analytics/models"
 	repository "thedefiant.io/analytics/repositories"
+	"thedefiant.io/analytics/utils"
 )
 
 type PostHandler struct {
@@ -16,8 +19,51 @@ func NewPostHandler(repo *repository.PostRepository) *PostHandler {
 	return &PostHandler{Repo: repo}
 }
 
+// GetPosts returns a page of posts. Supported query parameters: author, mainCategory,
+// subCategory, type, from and to (publish dates), window, sort, order (asc/desc), limit
+// and cursor (the nextCursor of the previous page). The yesterdayViews and lastNDaysViews
+// fields are each post's views during its first N days after publication, so
+// yesterdayViews is the views of its publication day.
 func (h *PostHandler) GetPosts(c *fiber.Ctx) error {
-	posts, err := h.Repo.GetPostsFromDatabase()
+	query := repository.PostQuery{
+		AuthorID:      c.Query("author"),
+		MainCategory:  c.Query("mainCategory"),
+		SubCategory:   c.Query("subCategory"),
+		Type:          c.Query("type"),
+		PublishedFrom: c.Query("from"),
+		PublishedTo:   c.Query("to"),
+		Window:        c.Query("window"),
+		SortBy:        c.Query("sort"),
+		Asc:           c.Query("order") == "asc",
+		Cursor:        c.Query("cursor"),
+	}
+	if query.Window != "" && utils.GetDaysFromRangeType(query.Window) == 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid window",
+		})
+	}
+	for _, date := range []string{query.PublishedFrom, query.PublishedTo} {
+		if _, err := utils.ParseDate(date); date != "" && err != nil {
+			return invalidDateRange(c)
+		}
+	}
+
+	limit, err := strconv.Atoi(c.Query("limit", "50"))
+	if err != nil || limit <= 0 || limit > 500 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid limit parameter",
+			"error":   "Limit must be an integer between 1 and 500",
+		})
+	}
+	query.Limit = limit
+
+	page, err := h.Repo.GetPostsFromDatabase(query)
+	if errors.Is(err, repository.ErrInvalidPostQuery) {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid query parameters",
+			"error":   err.Error(),
+		})
+	}
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Error fetching posts",
@@ -25,8 +71,10 @@ func (h *PostHandler) GetPosts(c *fiber.Ctx) error {
 		})
 	}
 	return c.JSON(fiber.Map{
-		"message": "Posts fetched successfully",
-		"data":    posts,
+		"message":    "Posts fetched successfully",
+		"data":       page.Posts,
+		"total":      page.Total,
+		"nextCursor": page.NextCursor,
 	})
 }
 
@@ -83,3 +131,102 @@ func (h *PostHandler) UpdateAnalytics(c *fiber.Ctx) error {
 	})
 }
 
+
+// GetPostDailyViews returns the daily view series of a post, defaulting to the last 30 days
+func (h *PostHandler) GetPostDailyViews(c *fiber.Ctx) error {
+	id := c.Params("id")
+	from, to, ok := parseDateRange(c)
+	if !ok {
+		return invalidDateRange(c)
+	}
+
+	views, err := h.Repo.GetPostDailyViews(id, from, to)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Error fetching daily views",
+			"error":   err.Error(),
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message": "Daily views fetched successfully",
+		"data":    views,
+	})
+}
+
+// GetPostSources returns where a post's traffic came from, defaulting to the last 30 days
+func (h *PostHandler) GetPostSources(c *fiber.Ctx) error {
+	id := c.Params("id")
+	from, to, ok := parseDateRange(c)
+	if !ok {
+		return invalidDateRange(c)
+	}
+
+	sources, err := h.Repo.GetPostTrafficSources(id, from, to)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Error fetching traffic sources",
+			"error":   err.Error(),
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message": "Traffic sources fetched successfully",
+		"data":    sources,
+	})
+}
+
+// GetPostViews returns every post's views between the from and to query dates. Pass
+// source=live to skip stored daily data and query GA directly.
+func (h *PostHandler) GetPostViews(c *fiber.Ctx) error {
+	from, to, ok := parseDateRange(c)
+	if !ok {
+		return invalidDateRange(c)
+	}
+	if to > utils.GetYesterdayDate() {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid date range",
+			"error":   "to must be yesterday or earlier",
+		})
+	}
+
+	views, source, err := h.Repo.GetViewsBetween(from, to, c.Query("source") == repository.ViewsSourceLive)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Error fetching post views",
+			"error":   err.Error(),
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message": "Post views fetched successfully",
+		"data":    views,
+		"from":    from,
+		"to":      to,
+		"source":  source,
+	})
+}
+
+// GetUnmatchedPaths lists GA paths with traffic that map to no known post
+func (h *PostHandler) GetUnmatchedPaths(c *fiber.Ctx) error {
+	from, to, ok := parseDateRange(c)
+	if !ok {
+		return invalidDateRange(c)
+	}
+	limit, err := strconv.Atoi(c.Query("limit", "100"))
+	if err != nil || limit <= 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid limit parameter",
+			"error":   "Limit must be a positive integer",
+		})
+	}
+
+	paths, err := h.Repo.GetUnmatchedPaths(from, to, limit)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Error fetching unmatched paths",
+			"error":   err.Error(),
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message": "Unmatched paths fetched successfully",
+		"data":    paths,
+	})
+}
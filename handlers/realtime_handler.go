@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	repository "thedefiant.io/analytics/repositories"
+)
+
+type RealtimeHandler struct {
+	Repo *repository.RealtimeRepository
+}
+
+func NewRealtimeHandler(repo *repository.RealtimeRepository) *RealtimeHandler {
+	return &RealtimeHandler{Repo: repo}
+}
+
+// GetTopPosts returns the posts readers are on right now
+func (h *RealtimeHandler) GetTopPosts(c *fiber.Ctx) error {
+	limit, err := strconv.Atoi(c.Query("limit", "10"))
+	if err != nil || limit <= 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid limit parameter",
+			"error":   "Limit must be a positive integer",
+		})
+	}
+
+	posts, fetchedAt, err := h.Repo.GetTopPosts()
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Error fetching realtime posts",
+			"error":   err.Error(),
+		})
+	}
+	if len(posts) > limit {
+		posts = posts[:limit]
+	}
+	return c.JSON(fiber.Map{
+		"message":   "Realtime posts fetched successfully",
+		"data":      posts,
+		"fetchedAt": fetchedAt,
+	})
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	repository "thedefiant.io/analytics/repositories"
+)
+
+type ReconcileHandler struct {
+	Repo *repository.PostRepository
+}
+
+func NewReconcileHandler(repo *repository.PostRepository) *ReconcileHandler {
+	return &ReconcileHandler{Repo: repo}
+}
+
+// GetPostDiff returns what a reconciliation with Sanity would change, without changing it
+func (h *ReconcileHandler) GetPostDiff(c *fiber.Ctx) error {
+	return h.reconcile(c, true)
+}
+
+// ReconcilePosts brings the posts table in line with Sanity and returns what changed
+func (h *ReconcileHandler) ReconcilePosts(c *fiber.Ctx) error {
+	return h.reconcile(c, false)
+}
+
+func (h *ReconcileHandler) reconcile(c *fiber.Ctx, dryRun bool) error {
+	diff, err := h.Repo.ReconcilePosts(dryRun)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Error reconciling posts",
+			"error":   err.Error(),
+		})
+	}
+	message := "Posts reconciled successfully"
+	if dryRun {
+		message = "Post diff computed successfully"
+	}
+	return c.JSON(fiber.Map{
+		"message": message,
+		"data":    diff,
+		"dryRun":  dryRun,
+	})
+}
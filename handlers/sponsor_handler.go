@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	repository "thedefiant.io/analytics/repositories"
+	"thedefiant.io/analytics/utils"
+)
+
+type SponsorHandler struct {
+	Repo *repository.PostRepository
+}
+
+func NewSponsorHandler(repo *repository.PostRepository) *SponsorHandler {
+	return &SponsorHandler{Repo: repo}
+}
+
+// GetSponsorReports returns the delivery report of every sponsored post published between
+// the from and to query dates (the last 90 days by default) over its contracted window.
+// Windows are stored per post with SetSponsorWindow; `days` (30 by default) is only used
+// for posts that have none. Pass format=csv to download a spreadsheet.
+func (h *SponsorHandler) GetSponsorReports(c *fiber.Ctx) error {
+	from := c.Query("from", utils.GetDateNDaysAgo(90))
+	to := c.Query("to", utils.FormatDate(time.Now().UTC()))
+	if !utils.IsValidDateRange(from, to) {
+		return invalidDateRange(c)
+	}
+	days, err := strconv.Atoi(c.Query("days", strconv.Itoa(repository.DefaultSponsorWindowDays)))
+	if err != nil || days <= 0 || days > 365 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid days parameter",
+			"error":   "Days must be an integer between 1 and 365",
+		})
+	}
+
+	reports, err := h.Repo.GetSponsorReports(from, to, days)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Error fetching sponsor reports",
+			"error":   err.Error(),
+		})
+	}
+
+	if c.Query("format") == "csv" {
+		body, err := sponsorReportsCSV(reports)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+				"message": "Error exporting sponsor reports",
+				"error":   err.Error(),
+			})
+		}
+		c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="sponsor-reports-%s-%s.csv"`, from, to))
+		return c.Send(body)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Sponsor reports fetched successfully",
+		"data":    reports,
+		"from":    from,
+		"to":      to,
+		"days":    days,
+	})
+}
+
+// SetSponsorWindow stores the contracted delivery window, in days, of a sponsored post
+func (h *SponsorHandler) SetSponsorWindow(c *fiber.Ctx) error {
+	days, err := strconv.Atoi(c.Query("days"))
+	if err != nil || days <= 0 || days > 365 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid days parameter",
+			"error":   "Days must be an integer between 1 and 365",
+		})
+	}
+
+	post, err := h.Repo.SetSponsorWindow(c.Params("id"), days)
+	if errors.Is(err, repository.ErrSponsorPostNotFound) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"message": "Sponsored post not found",
+		})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Error storing sponsor window",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Sponsor window stored successfully",
+		"data":    post,
+	})
+}
+
+// sponsorReportsCSV writes one row per sponsored post, with a views column for every
+// channel group that appears in any of the reports
+func sponsorReportsCSV(reports []repository.SponsorReport) ([]byte, error) {
+	var channelGroups []string
+	seen := make(map[string]bool)
+	for _, report := range reports {
+		for _, channel := range report.Channels {
+			if !seen[channel.ChannelGroup] {
+				seen[channel.ChannelGroup] = true
+				channelGroups = append(channelGroups, channel.ChannelGroup)
+			}
+		}
+	}
+
+	header := []string{
+		"Post ID", "Title", "URL Path", "Published At", "Window Days", "Window Start", "Window End", "Complete",
+		"Views", "Users", "Sessions", "Engagement Time (s)", "Avg Engagement Time (s)", "Engagement Rate",
+	}
+	for _, group := range channelGroups {
+		header = append(header, group+" Views")
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(header); err != nil {
+		return nil, err
+	}
+	for _, report := range reports {
+		record := []string{
+			deref(report.PostID),
+			deref(report.Title),
+			"/" + deref(report.MainCategory) + "/" + deref(report.SubCategory) + "/" + deref(report.Slug),
+			report.PublishedAt.Format(time.RFC3339),
+			strconv.Itoa(report.WindowDays),
+			report.WindowStart,
+			report.WindowEnd,
+			strconv.FormatBool(report.Complete),
+			strconv.FormatInt(report.Views, 10),
+			strconv.FormatInt(report.Users, 10),
+			strconv.FormatInt(report.Sessions, 10),
+			strconv.FormatFloat(report.EngagementDuration, 'f', 0, 64),
+			strconv.FormatFloat(report.AverageEngagementTime, 'f', 1, 64),
+			strconv.FormatFloat(report.EngagementRate, 'f', 4, 64),
+		}
+		channelViews := make(map[string]int64, len(report.Channels))
+		for _, channel := range report.Channels {
+			channelViews[channel.ChannelGroup] = channel.Views
+		}
+		for _, group := range channelGroups {
+			record = append(record, strconv.FormatInt(channelViews[group], 10))
+		}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	repository "thedefiant.io/analytics/repositories"
+)
+
+type TopicHandler struct {
+	Repo *repository.TopicRepository
+}
+
+func NewTopicHandler(repo *repository.TopicRepository) *TopicHandler {
+	return &TopicHandler{Repo: repo}
+}
+
+// GetTopics returns the views of every tag, defaulting to the last 30 days
+func (h *TopicHandler) GetTopics(c *fiber.Ctx) error {
+	from, to, ok := parseDateRange(c)
+	if !ok {
+		return invalidDateRange(c)
+	}
+
+	topics, err := h.Repo.GetTopics(from, to)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Error fetching topics",
+			"error":   err.Error(),
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message": "Topics fetched successfully",
+		"data":    topics,
+		"from":    from,
+		"to":      to,
+	})
+}
+
+// GetTopic returns the views of one tag and of each of its posts, defaulting to the last 30 days
+func (h *TopicHandler) GetTopic(c *fiber.Ctx) error {
+	from, to, ok := parseDateRange(c)
+	if !ok {
+		return invalidDateRange(c)
+	}
+
+	topic, err := h.Repo.GetTopic(c.Params("slug"), from, to)
+	if errors.Is(err, repository.ErrTopicNotFound) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"message": "Topic not found",
+		})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Error fetching topic",
+			"error":   err.Error(),
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message": "Topic fetched successfully",
+		"data":    topic,
+		"from":    from,
+		"to":      to,
+	})
+}
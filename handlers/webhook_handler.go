@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	repository "thedefiant.io/analytics/repositories"
+	"thedefiant.io/analytics/services/sanity"
+)
+
+// WebhookHandler receives Sanity webhooks and syncs the affected document right away.
+// The daily CreatePost cron still runs as a reconciliation pass for missed deliveries.
+type WebhookHandler struct {
+	Posts   *repository.PostRepository
+	Authors *repository.AuthorRepository
+	// Secret is the signing secret configured on the Sanity webhook
+	Secret string
+}
+
+func NewWebhookHandler(posts *repository.PostRepository, authors *repository.AuthorRepository, secret string) *WebhookHandler {
+	return &WebhookHandler{Posts: posts, Authors: authors, Secret: secret}
+}
+
+// sanityWebhookPayload is the webhook projection. Only _id and _type are required; with
+// the projection {_id, _type, "operation": delta::operation()} the logs also name the
+// operation, which Sanity otherwise only sends in the optional sanity-operation header.
+type sanityWebhookPayload struct {
+	ID        string `json:"_id"`
+	Type      string `json:"_type"`
+	Operation string `json:"operation"`
+}
+
+// SanityWebhook handles create, update and delete notifications. The document is re-read
+// from Sanity instead of trusting the payload, so publishing stores the current version
+// while unpublishing or deleting (the published document is gone either way) soft-deletes it.
+func (h *WebhookHandler) SanityWebhook(c *fiber.Ctx) error {
+	if h.Secret == "" {
+		log.Println("Rejecting Sanity webhook: SANITY_WEBHOOK_SECRET is not set")
+		return c.Status(http.StatusServiceUnavailable).JSON(fiber.Map{
+			"message": "Webhook secret is not configured",
+		})
+	}
+	if err := sanity.VerifyWebhookSignature(c.Get(sanity.WebhookSignatureHeader), c.Body(), h.Secret); err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Invalid webhook signature",
+			"error":   err.Error(),
+		})
+	}
+
+	var payload sanityWebhookPayload
+	if err := json.Unmarshal(c.Body(), &payload); err != nil || payload.ID == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid webhook payload",
+			"error":   "Body must contain the document _id and _type",
+		})
+	}
+	operation := payload.Operation
+	if operation == "" {
+		operation = c.Get("sanity-operation", "change")
+	}
+
+	// Drafts are not tracked; their publish arrives as a separate event
+	if strings.HasPrefix(payload.ID, "drafts.") {
+		return c.JSON(fiber.Map{
+			"message": "Draft ignored",
+		})
+	}
+
+	var data interface{}
+	var err error
+	switch payload.Type {
+	case "blog", "sponsor":
+		data, err = h.Posts.SyncPost(payload.ID)
+	case "author":
+		data, err = h.Authors.SyncAuthor(payload.ID)
+	default:
+		return c.JSON(fiber.Map{
+			"message": "Document type ignored",
+		})
+	}
+	if err != nil {
+		log.Printf("Error syncing Sanity %s %s after %s: %v", payload.Type, payload.ID, operation, err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Error syncing document",
+			"error":   err.Error(),
+		})
+	}
+
+	log.Printf("Synced Sanity %s %s after %s", payload.Type, payload.ID, operation)
+	return c.JSON(fiber.Map{
+		"message": "Document synced successfully",
+		"data":    data,
+	})
+}
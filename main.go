@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -17,6 +18,7 @@ import (
 	"thedefiant.io/analytics/services/analytics"
 	"thedefiant.io/analytics/services/beehiiv"
 	"thedefiant.io/analytics/services/sanity"
+	"thedefiant.io/analytics/utils"
 )
 
 func main() {
@@ -42,10 +44,13 @@ func main() {
 	}
 
 	// Auto Migrate
-	err = db.AutoMigrate(&models.Post{}, &models.Author{}, &models.BeehiivPostMetrics{})
+	err = db.AutoMigrate(&models.Post{}, &models.Author{}, &models.AuthorViews{}, &models.PostDailyView{}, &models.BackfillJob{}, &models.PostWindowSync{}, &models.PostTrafficSource{}, &models.PostAudience{}, &models.PostPath{}, &models.PostAuthor{}, &models.Tag{}, &models.PostTag{})
 	if err != nil {
 		log.Fatalf("Failed to auto migrate: %v", err)
 	}
+	if err := models.MigrateBeehiivMetrics(db); err != nil {
+		log.Fatalf("Failed to migrate Beehiiv metrics: %v", err)
+	}
 
 	sanityClient, err := sanity.NewClient()
 	if err != nil {
@@ -65,15 +70,51 @@ func main() {
 	postRepo := repository.NewPostRepository(db, sanityClient, analyticsClient)
 	authorRepo := repository.NewAuthorRepository(db, sanityClient, analyticsClient)
 	beehiivRepo := repository.NewBeehiivMetricsRepository(db, beehiivClient)
+	audienceRepo := repository.NewAudienceRepository(db, analyticsClient)
+	realtimeRepo := repository.NewRealtimeRepository(db, analyticsClient)
+	topicRepo := repository.NewTopicRepository(db)
+
+	// Posts stored before co-authors were tracked only know their primary author
+	if err := postRepo.SeedPostAuthors(); err != nil {
+		log.Printf("Failed to seed post authors: %v", err)
+	}
 
 	postHandler := handlers.NewPostHandler(postRepo)
 	authorHandler := handlers.NewAuthorHandler(authorRepo)
 	beehiivHandler := handlers.NewBeehiivHandler(beehiivRepo)
+	backfillHandler := handlers.NewBackfillHandler(postRepo)
+	audienceHandler := handlers.NewAudienceHandler(audienceRepo)
+	realtimeHandler := handlers.NewRealtimeHandler(realtimeRepo)
+	reconcileHandler := handlers.NewReconcileHandler(postRepo)
+	sponsorHandler := handlers.NewSponsorHandler(postRepo)
+	topicHandler := handlers.NewTopicHandler(topicRepo)
+	categoryHandler := handlers.NewCategoryHandler(postRepo)
+	webhookHandler := handlers.NewWebhookHandler(postRepo, authorRepo, os.Getenv("SANITY_WEBHOOK_SECRET"))
+
+	// Subcommands run once and exit instead of starting the server
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "backfill":
+			if err := runBackfillCommand(postRepo, os.Args[2:]); err != nil {
+				log.Fatalf("Backfill failed: %v", err)
+			}
+			log.Println("Backfill completed successfully")
+		case "author-reports":
+			if err := runAuthorReportsCommand(authorRepo, os.Args[2:]); err != nil {
+				log.Fatalf("Author report backfill failed: %v", err)
+			}
+			log.Println("Author report backfill completed successfully")
+		default:
+			log.Fatalf("Unknown command: %s", os.Args[1])
+		}
+		return
+	}
 
 	// Set up cron jobs
 	cronJob := cron.New(cron.WithLocation(time.UTC))
 
-	// Fetch posts daily at 8:00 PM UTC
+	// Fetch posts daily at 8:00 PM UTC. The Sanity webhook stores posts as they are
+	// published; this pass picks up anything a missed delivery left out.
 	_, err = cronJob.AddFunc("0 20 * * *", func() {
 		log.Println("Fetching posts")
 		_, err := postRepo.CreatePost()
@@ -86,103 +127,83 @@ func main() {
 		log.Printf("Error setting up post fetch cron job: %v", err)
 	}
 
-	// Update analytics data
-	_, err = cronJob.AddFunc("10 23 * * *", func() {
-		log.Println("Updating yesterday's analytics")
-		_, err := postRepo.UpdateYesterdayViews()
+	// Soft-delete posts removed or unpublished in Sanity and add any the fetch above missed
+	_, err = cronJob.AddFunc("30 20 * * *", func() {
+		log.Println("Reconciling posts with Sanity")
+		_, err := postRepo.ReconcilePosts(false)
 		if err != nil {
-			log.Printf("Error updating yesterday's views: %v", err)
+			log.Printf("Error reconciling posts with Sanity: %v", err)
+			return
 		}
-		log.Println("Yesterday's analytics updated successfully")
+		log.Println("Posts reconciled with Sanity successfully")
 	})
 	if err != nil {
-		log.Printf("Error setting up yesterday analytics update cron job: %v", err)
+		log.Printf("Error setting up post reconciliation cron job: %v", err)
 	}
 
-	_, err = cronJob.AddFunc("20 23 * * *", func() {
-		log.Println("Updating last 7 days analytics")
-		_, err := postRepo.UpdateLastSevenDaysViews()
+	// Store yesterday's per-post views and derive the rolling windows from them
+	_, err = cronJob.AddFunc("10 23 * * *", func() {
+		log.Println("Updating daily post views")
+		_, err := postRepo.UpdateDailyViews(utils.GetYesterdayDate())
 		if err != nil {
-			log.Printf("Error updating last 7 days views: %v", err)
+			log.Printf("Error updating daily post views: %v", err)
+			return
 		}
-		log.Println("Last 7 days analytics updated successfully")
-	})
-	if err != nil {
-		log.Printf("Error setting up last 7 days analytics update cron job: %v", err)
-	}
+		log.Println("Daily post views updated successfully")
 
-	_, err = cronJob.AddFunc("30 23 * * *", func() {
-		log.Println("Fetching Google Analytics data For Last 14 Days")
-		_, err := postRepo.UpdateLast14DaysViews()
+		log.Println("Updating post traffic sources")
+		_, err = postRepo.UpdateTrafficSources(utils.GetYesterdayDate())
 		if err != nil {
-			log.Printf("Error fetching Google Analytics data: %v", err)
+			log.Printf("Error updating post traffic sources: %v", err)
+		} else {
+			log.Println("Post traffic sources updated successfully")
 		}
-		log.Println("Google Analytics data fetched successfully for Last 14 Days")
-	})
-	if err != nil {
-		log.Printf("Error setting up last 14 days analytics update cron job: %v", err)
-	}
 
-	_, err = cronJob.AddFunc("40 23 * * *", func() {
-		log.Println("Fetching Google Analytics data For Last 30 Days")
-		_, err := postRepo.UpdateLast30DaysViews()
+		log.Println("Updating post audience")
+		_, err = audienceRepo.UpdateAudience(utils.GetYesterdayDate())
 		if err != nil {
-			log.Printf("Error fetching Google Analytics data: %v", err)
+			log.Printf("Error updating post audience: %v", err)
+		} else {
+			log.Println("Post audience updated successfully")
 		}
-		log.Println("Google Analytics data fetched successfully for Last 30 Days")
-	})
-	if err != nil {
-		log.Printf("Error setting up last 30 days analytics update cron job: %v", err)
-	}
 
-	_, err = cronJob.AddFunc("50 23 * * *", func() {
-		log.Println("Fetching Google Analytics data For Last 90 Days")
-		_, err := postRepo.UpdateLast90DaysViews()
+		log.Println("Refreshing rolling window views")
+		err = postRepo.RefreshRollingViews()
 		if err != nil {
-			log.Printf("Error fetching Google Analytics data: %v", err)
+			log.Printf("Error refreshing rolling window views: %v", err)
+			return
 		}
-		log.Println("Google Analytics data fetched successfully for Last 90 Days")
-	})
-	if err != nil {
-		log.Printf("Error setting up last 90 days analytics update cron job: %v", err)
-	}
+		log.Println("Rolling window views refreshed successfully")
 
-	_, err = cronJob.AddFunc("0 0 * * *", func() {
-		log.Println("Fetching Google Analytics data For Last 180 Days")
-		_, err := postRepo.UpdateLast180DaysViews()
+		log.Println("Fetching GA totals for windows closed yesterday")
+		_, err = postRepo.UpdateClosedWindowViews()
 		if err != nil {
-			log.Printf("Error fetching Google Analytics data: %v", err)
+			log.Printf("Error fetching GA totals for closed windows: %v", err)
+			return
 		}
-		log.Println("Google Analytics data fetched successfully for Last 180 Days")
+		log.Println("GA totals for closed windows fetched successfully")
 	})
 	if err != nil {
-		log.Printf("Error setting up last 180 days analytics update cron job: %v", err)
+		log.Printf("Error setting up daily views cron job: %v", err)
 	}
 
-	_, err = cronJob.AddFunc("10 0 * * *", func() {
-		log.Println("Fetching Google Analytics data For Last 365 Days")
-		_, err := postRepo.UpdateLast365DaysViews()
+	// Report the calendar month that just ended. Authors are fetched first so new ones
+	// get a row too.
+	_, err = cronJob.AddFunc("0 6 1 * *", func() {
+		_, err := authorRepo.CreateAuthor()
 		if err != nil {
-			log.Printf("Error fetching Google Analytics data: %v", err)
+			log.Printf("Error fetching authors: %v", err)
 		}
-		log.Println("Google Analytics data fetched successfully for Last 365 Days")
-	})
-	if err != nil {
-		log.Printf("Error setting up last 365 days analytics update cron job: %v", err)
-	}
-	_, err = cronJob.AddFunc("0 6 1 * *", func() {
-		log.Println("Updating monthly author analytics")
-		_, err := authorRepo.UpdateAnalyticsViews()
+		log.Println("Authors fetched successfully")
+
+		month := time.Now().UTC().AddDate(0, -1, 0).Format("2006-01")
+		log.Printf("Updating monthly author analytics for %s", month)
+		_, err = authorRepo.StoreMonthlyAuthorViews(month)
 		if err != nil {
 			log.Printf("Error updating monthly author analytics: %v", err)
 		} else {
 			log.Println("Monthly author analytics updated successfully")
 		}
-		_, err = authorRepo.CreateAuthor()
-		if err != nil {
-			log.Printf("Error fetching authors: %v", err)
-		}
-		log.Println("Authors fetched successfully")
 	})
 	if (err != nil) {
 		log.Printf("Error setting up monthly author analytics update cron job: %v", err)
@@ -190,9 +211,12 @@ func main() {
 
 	_, err = cronJob.AddFunc("0 12 * * 0", func() {
 		log.Println("Fetching Beehiiv metrics")
-		err := beehiivRepo.UpdatePostMetrics()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+		defer cancel()
+		err := beehiivRepo.UpdatePostMetrics(ctx)
 		if err != nil {
 			log.Printf("Error fetching Beehiiv metrics: %v", err)
+			return
 		}
 		log.Println("Beehiiv metrics fetched successfully")
 	})
@@ -205,17 +229,49 @@ func main() {
 	// Start the cron job scheduler
 	cronJob.Start()
 
+	// Fill windows left incomplete by runs missed during downtime or deploys
+	go func() {
+		log.Println("Catching up missed daily views")
+		if err := postRepo.CatchUpViews(); err != nil {
+			log.Printf("Error catching up missed daily views: %v", err)
+			return
+		}
+		log.Println("Missed daily views caught up successfully")
+	}()
+
 	app := fiber.New()
 
 	// Post routes
 	app.Get("/api/posts", postHandler.GetPosts)
+	app.Get("/api/posts/views", postHandler.GetPostViews)
+	app.Get("/api/posts/unmatched-paths", postHandler.GetUnmatchedPaths)
 	app.Post("/api/posts", postHandler.CreatePost)
 	app.Post("/api/posts/update-analytics", postHandler.UpdateAnalytics)
+	app.Get("/api/posts/:id/daily-views", postHandler.GetPostDailyViews)
+	app.Get("/api/posts/:id/sources", postHandler.GetPostSources)
+	app.Get("/api/posts/:id/audience", audienceHandler.GetPostAudience)
 
 	// Author routes
 	app.Get("/api/authors", authorHandler.GetAuthors)
 	app.Post("/api/authors", authorHandler.CreateAuthor)
+	app.Get("/api/authors/reports", authorHandler.GetMonthlyReports)
 	app.Get("/api/authors/:id", authorHandler.GetAuthorByID)
+	app.Get("/api/authors/:id/audience", audienceHandler.GetAuthorAudience)
+
+	// Category routes
+	app.Get("/api/categories", categoryHandler.GetCategories)
+	app.Get("/api/categories/:category", categoryHandler.GetCategory)
+	app.Get("/api/categories/:category/audience", audienceHandler.GetCategoryAudience)
+
+	// Topic routes
+	app.Get("/api/topics", topicHandler.GetTopics)
+	app.Get("/api/topics/:slug", topicHandler.GetTopic)
+
+	// Sponsors
+	app.Get("/api/sponsors/reports", sponsorHandler.GetSponsorReports)
+
+	// Realtime
+	app.Get("/api/realtime/top-posts", realtimeHandler.GetTopPosts)
 
 	// Beehiiv
 	app.Get("/api/beehiiv/update",beehiivHandler.UpdatePostMetrics)
@@ -225,6 +281,18 @@ func main() {
 	app.Get("/api/beehiiv/free-month", beehiivHandler.GeMonthFreeMetrics)
 	app.Get("/api/beehiiv/alpha-month", beehiivHandler.GeMonthAlphaMetrics)
 
+	// Webhooks
+	app.Post("/api/webhooks/sanity", webhookHandler.SanityWebhook)
+
+	// Admin
+	app.Get("/api/admin/backfill", backfillHandler.GetBackfills)
+	app.Post("/api/admin/backfill", backfillHandler.StartBackfill)
+	app.Post("/api/admin/backfill/:id/resume", backfillHandler.ResumeBackfill)
+	app.Post("/api/admin/author-reports/backfill", authorHandler.BackfillMonthlyReports)
+	app.Get("/api/admin/reconcile", reconcileHandler.GetPostDiff)
+	app.Post("/api/admin/reconcile", reconcileHandler.ReconcilePosts)
+	app.Put("/api/admin/sponsors/:id/window", sponsorHandler.SetSponsorWindow)
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8000" // Default port if not specified
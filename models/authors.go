@@ -5,37 +5,25 @@ import (
 
 	"gorm.io/gorm"
 )
-type InputAuthor struct {
-	ID         string `json:"_id"`
-	Name       string `json:"name"`
-	PostsSlugs []struct {
-		Slug         string `json:"slug"`
-		MainCategory string `json:"mainCategory"`
-		SubCategory  string `json:"subCategory"`
-	} `json:"postsSlugs"`
-}
-
-type OutputAuthor struct {
-	ID   string   `json:"id"`
-	Slugs []string `json:"slug"`
-}
-
-type AnalyticsReponse struct{
-	Views int64 `json:"views"`
-	ID string `json:"id"`
-}
-
 type Author struct {
 	ID *string `json:"id"`
     Name   *string     `json:"name" gorm:"column:name"`
 	CreatedAt time.Time `json:"createdAt"`
+	// DeletedAt is set when the author is deleted in Sanity
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 }
+// AuthorViews is the views credited to an author over one period. Monthly reports cover
+// a calendar month from PeriodStart to PeriodEnd; rows stored before those columns existed
+// leave them empty and cover the 30 days before CreatedAt.
 type AuthorViews struct {
 	ID uint `gorm:"primaryKey"`
-	AuthorId *string `json:"authorId"`
-	Author Author `gorm:"foreignKey:AuthorId"`
+	AuthorId *string `json:"authorId" gorm:"uniqueIndex:idx_author_views_period"`
+	Author Author `json:"-" gorm:"foreignKey:AuthorId"`
 	Views int64 `json:"views"`
+	PeriodStart *time.Time `json:"periodStart" gorm:"type:date;uniqueIndex:idx_author_views_period"`
+	PeriodEnd *time.Time `json:"periodEnd" gorm:"type:date;uniqueIndex:idx_author_views_period"`
 	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
 }
 
 func MigrateAuthors(db *gorm.DB) error {
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	BackfillStatusPending   = "pending"
+	BackfillStatusRunning   = "running"
+	BackfillStatusCompleted = "completed"
+	BackfillStatusFailed    = "failed"
+)
+
+// BackfillJob tracks a Google Analytics backfill over an explicit date range.
+// NextDate is the first day that still has to be fetched, so an interrupted job
+// can be resumed from where it stopped.
+type BackfillJob struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	StartDate time.Time `json:"startDate" gorm:"type:date"`
+	EndDate   time.Time `json:"endDate" gorm:"type:date"`
+	NextDate  time.Time `json:"nextDate" gorm:"type:date"`
+	ChunkDays int       `json:"chunkDays"`
+	Status    string    `json:"status" gorm:"index"`
+	Error     string    `json:"error"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func MigrateBackfillJobs(db *gorm.DB) error {
+	return db.AutoMigrate(&BackfillJob{})
+}
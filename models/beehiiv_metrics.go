@@ -1,18 +1,24 @@
 package models
 
 import (
+	"fmt"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// LatestBeehiivPostMetricsView is the view holding the most recent snapshot of each post
+const LatestBeehiivPostMetricsView = "beehiiv_latest_post_metrics"
+
+// BeehiivPostMetrics is a snapshot of a post's Beehiiv stats, taken at CapturedAt. Every
+// sync stores a new snapshot so opens and clicks can be followed after a send.
 type BeehiivPostMetrics struct {
 	ID              uint      `gorm:"primaryKey"`
-	PostID          string    `json:"post_id" gorm:"uniqueIndex"`
+	PostID          string    `json:"post_id" gorm:"uniqueIndex:idx_beehiiv_metrics_snapshot,priority:1"`
 	Title           string    `json:"title"`
 	Slug            string    `json:"slug"`
 	PublishDate     time.Time `json:"publish_date"`
-	
+
 	// Email metrics
 	EmailRecipients    int     `json:"email_recipients"`
 	EmailDelivered     int     `json:"email_delivered"`
@@ -22,18 +28,55 @@ type BeehiivPostMetrics struct {
 	EmailUniqueClicks  int     `json:"email_unique_clicks"`
 	EmailOpenRate      float64 `json:"email_open_rate"`
 	EmailClickRate     float64 `json:"email_click_rate"`
-	
+
 	// Web metrics
 	WebViews          int     `json:"web_views"`
 	WebClicks         int     `json:"web_clicks"`
 	WebClickRate      float64 `json:"web_click_rate"`
-	
+
 	// Combined metrics
 	TotalEngagements  int     `json:"total_engagements"`
-	
+
+	CapturedAt       time.Time `json:"captured_at" gorm:"uniqueIndex:idx_beehiiv_metrics_snapshot,priority:2"`
 	CreatedAt        time.Time `json:"created_at"`
 }
 
+// latestBeehiivPostMetricsColumns are the columns of the latest view. They are listed so
+// the view only depends on them, and it is recreated on every migration anyway so column
+// changes on the table never trip over it.
+const latestBeehiivPostMetricsColumns = `id, post_id, title, slug, publish_date,
+	email_recipients, email_delivered, email_opens, email_unique_opens, email_clicks,
+	email_unique_clicks, email_open_rate, email_click_rate,
+	web_views, web_clicks, web_click_rate, total_engagements, captured_at, created_at`
+
+// MigrateBeehiivMetrics migrates the snapshots table and recreates the latest view around
+// it. Tables from before snapshots had one row per post: the unique index on post_id is
+// dropped and those rows become snapshots captured when they were created.
 func MigrateBeehiivMetrics(db *gorm.DB) error {
-	return db.AutoMigrate(&BeehiivPostMetrics{})
-}
\ No newline at end of file
+	if err := db.Exec(`DROP VIEW IF EXISTS ` + LatestBeehiivPostMetricsView).Error; err != nil {
+		return fmt.Errorf("failed to drop latest metrics view: %w", err)
+	}
+	if err := db.AutoMigrate(&BeehiivPostMetrics{}); err != nil {
+		return err
+	}
+	migrator := db.Migrator()
+	if migrator.HasIndex(&BeehiivPostMetrics{}, "idx_beehiiv_post_metrics_post_id") {
+		if err := migrator.DropIndex(&BeehiivPostMetrics{}, "idx_beehiiv_post_metrics_post_id"); err != nil {
+			return fmt.Errorf("failed to drop unique post index: %w", err)
+		}
+	}
+	err := db.Model(&BeehiivPostMetrics{}).
+		Where("captured_at IS NULL").
+		Update("captured_at", gorm.Expr("created_at")).Error
+	if err != nil {
+		return fmt.Errorf("failed to backfill capture times: %w", err)
+	}
+	err = db.Exec(`CREATE VIEW ` + LatestBeehiivPostMetricsView + ` AS
+		SELECT DISTINCT ON (post_id) ` + latestBeehiivPostMetricsColumns + `
+		FROM beehiiv_post_metrics
+		ORDER BY post_id, captured_at DESC`).Error
+	if err != nil {
+		return fmt.Errorf("failed to create latest metrics view: %w", err)
+	}
+	return nil
+}
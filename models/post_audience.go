@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PostAudience holds the traffic a post received on one day from readers in a single
+// country on a single device category
+type PostAudience struct {
+	ID             uint      `gorm:"primaryKey"`
+	PostID         *string   `json:"postId" gorm:"uniqueIndex:idx_post_audiences_key"`
+	Post           Post      `json:"-" gorm:"foreignKey:PostID"`
+	Date           time.Time `json:"date" gorm:"type:date;uniqueIndex:idx_post_audiences_key"`
+	Country        string    `json:"country" gorm:"uniqueIndex:idx_post_audiences_key"`
+	DeviceCategory string    `json:"deviceCategory" gorm:"uniqueIndex:idx_post_audiences_key"`
+	Views          int64     `json:"views"`
+	Sessions       int64     `json:"sessions"`
+	Users          int64     `json:"users"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+func MigratePostAudiences(db *gorm.DB) error {
+	return db.AutoMigrate(&PostAudience{})
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// PostAuthor credits an author with a post. Post.AuthorId keeps the primary author;
+// this table lists every contributor, the primary author at position 0.
+type PostAuthor struct {
+	PostID   *string `json:"postId" gorm:"primaryKey"`
+	Post     Post    `json:"-" gorm:"foreignKey:PostID"`
+	AuthorID *string `json:"authorId" gorm:"primaryKey;index"`
+	Position int     `json:"position"`
+}
+
+func MigratePostAuthors(db *gorm.DB) error {
+	return db.AutoMigrate(&PostAuthor{})
+}
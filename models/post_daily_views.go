@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PostDailyView holds the Google Analytics metrics a post received on a single day.
+// The rolling view columns on Post are derived from these rows. Durations are in seconds
+// and kept as totals so they can be summed over any period. Partial is set when GA
+// sampled or thresholded the report a row came from.
+type PostDailyView struct {
+	ID                 uint      `gorm:"primaryKey"`
+	PostID             *string   `json:"postId" gorm:"uniqueIndex:idx_post_daily_views_post_date"`
+	Post               Post      `json:"-" gorm:"foreignKey:PostID"`
+	Date               time.Time `json:"date" gorm:"type:date;uniqueIndex:idx_post_daily_views_post_date;index"`
+	Views              int64     `json:"views"`
+	Users              int64     `json:"users"`
+	Sessions           int64     `json:"sessions"`
+	EngagedSessions    int64     `json:"engagedSessions"`
+	EngagementDuration float64   `json:"engagementDuration"`
+	SessionDuration    float64   `json:"sessionDuration"`
+	Partial            bool      `json:"partial"`
+	CreatedAt          time.Time `json:"createdAt"`
+	UpdatedAt          time.Time `json:"updatedAt"`
+}
+
+// PostMetrics are engagement metrics aggregated from post_daily_views. Users is the sum
+// of daily users, so a reader who returns on several days is counted once per day.
+type PostMetrics struct {
+	Users                  int64   `json:"users"`
+	Sessions               int64   `json:"sessions"`
+	EngagementDuration     float64 `json:"engagementDuration"`
+	AverageSessionDuration float64 `json:"averageSessionDuration"`
+	EngagementRate         float64 `json:"engagementRate"`
+	BounceRate             float64 `json:"bounceRate"`
+}
+
+func MigratePostDailyViews(db *gorm.DB) error {
+	return db.AutoMigrate(&PostDailyView{})
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PostPath is a GA pagePath a post has been served under. A row is added whenever a
+// post's slug or categories change, so views on old URLs can still be attributed.
+type PostPath struct {
+	ID        uint      `gorm:"primaryKey"`
+	PostID    *string   `json:"postId" gorm:"uniqueIndex:idx_post_paths_post_path"`
+	Post      Post      `json:"-" gorm:"foreignKey:PostID"`
+	Path      string    `json:"path" gorm:"uniqueIndex:idx_post_paths_post_path;index"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func MigratePostPaths(db *gorm.DB) error {
+	return db.AutoMigrate(&PostPath{})
+}
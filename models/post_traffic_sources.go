@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PostTrafficSource holds the traffic a post received on one day from a single
+// GA session source / medium / default channel group combination
+type PostTrafficSource struct {
+	ID           uint      `gorm:"primaryKey"`
+	PostID       *string   `json:"postId" gorm:"uniqueIndex:idx_post_traffic_sources_key"`
+	Post         Post      `json:"-" gorm:"foreignKey:PostID"`
+	Date         time.Time `json:"date" gorm:"type:date;uniqueIndex:idx_post_traffic_sources_key"`
+	Source       string    `json:"source" gorm:"uniqueIndex:idx_post_traffic_sources_key"`
+	Medium       string    `json:"medium" gorm:"uniqueIndex:idx_post_traffic_sources_key"`
+	ChannelGroup string    `json:"channelGroup" gorm:"uniqueIndex:idx_post_traffic_sources_key"`
+	Views        int64     `json:"views"`
+	Sessions     int64     `json:"sessions"`
+	Users        int64     `json:"users"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+func MigratePostTrafficSources(db *gorm.DB) error {
+	return db.AutoMigrate(&PostTrafficSource{})
+}
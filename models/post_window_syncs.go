@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Sources of a window's views
+const (
+	// WindowSourceDaily windows are summed from post_daily_views
+	WindowSourceDaily = "daily"
+	// WindowSourceGA windows hold GA's own total, set once the window closed
+	WindowSourceGA = "ga"
+)
+
+// PostWindowSync is the high-water mark of a post's view window: the last day of the
+// window that was covered by post_daily_views when the window column was derived.
+// A window is complete once SyncedThrough reaches publication day + N - 1. Source tells
+// where the window column came from; daily sums never overwrite a GA total.
+type PostWindowSync struct {
+	ID            uint      `gorm:"primaryKey"`
+	PostID        *string   `json:"postId" gorm:"uniqueIndex:idx_post_window_syncs_post_range"`
+	RangeType     string    `json:"rangeType" gorm:"uniqueIndex:idx_post_window_syncs_post_range"`
+	SyncedThrough time.Time `json:"syncedThrough" gorm:"type:date"`
+	Source        string    `json:"source" gorm:"default:daily"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+func MigratePostWindowSyncs(db *gorm.DB) error {
+	return db.AutoMigrate(&PostWindowSync{})
+}
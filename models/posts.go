@@ -6,15 +6,39 @@ import (
 	"gorm.io/gorm"
 )
 
+// Post types, the Sanity _type of the document
+const (
+	PostTypeBlog    = "blog"
+	PostTypeSponsor = "sponsor"
+)
 
 type Post struct {
 	ID *string `json:"id"`
 	Title *string `json:"title"`
 	Slug *string `json:"slug"`
 	AuthorId *string `json:"authorId"`
+	// AuthorIds lists every author referenced by the Sanity document, primary author first.
+	// It is only filled when reading from Sanity; post_authors stores it.
+	AuthorIds []string `json:"authorIds,omitempty" gorm:"-"`
+	// AuthorRefCount is the length of the Sanity authors array, resolved or not
+	AuthorRefCount int `json:"authorRefCount,omitempty" gorm:"-"`
+	// Tags are the tags and topics of the Sanity document. Like AuthorIds it is only filled
+	// when reading from Sanity; post_tags stores it.
+	Tags []TagRef `json:"tags,omitempty" gorm:"-"`
+	// TagRefCount is the length of the Sanity tags and topics arrays, resolved or not
+	TagRefCount int `json:"tagRefCount,omitempty" gorm:"-"`
 	MainCategory *string `json:"mainCategory"`
 	SubCategory *string `json:"subCategory"`
+	Type *string `json:"type" gorm:"index"`
+	// SponsorWindowDays is the contracted delivery window of a sponsored post. It is set
+	// through the admin API, not Sanity; sponsor reports use their default when it is nil.
+	SponsorWindowDays *int `json:"sponsorWindowDays,omitempty"`
 	PublishedAt time.Time `json:"publishedAt"`
+	// YesterdayViews and the LastNDaysViews columns are the views a post received during
+	// its first N days after publication, not over the last N days. YesterdayViews is
+	// therefore the views of the publication day. They used to be filled by asking GA for
+	// the last N days of posts published exactly N days ago, which gives the same numbers;
+	// they are now summed from post_daily_views.
 	YesterdayViews     int64     `json:"yesterdayViews" gorm:"column:yesterday_views"`
     LastSevenDaysViews int64     `json:"lastSevenDaysViews" gorm:"column:last_seven_days_views"`
     Last14DaysViews    int64     `json:"last14DaysViews" gorm:"column:last_14_days_views"`
@@ -23,6 +47,8 @@ type Post struct {
     Last180DaysViews   int64     `json:"last180DaysViews" gorm:"column:last_180_days_views"`
     Last365DaysViews   int64     `json:"last365DaysViews" gorm:"column:last_365_days_views"`
 	CreatedAt time.Time `json:"createdAt"`
+	// DeletedAt is set when the post is unpublished or deleted in Sanity
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
 func MigratePosts(db *gorm.DB) error {
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Tag is a Sanity tag or topic that posts are filed under
+type Tag struct {
+	ID        uint      `json:"-" gorm:"primaryKey"`
+	Slug      string    `json:"slug" gorm:"uniqueIndex"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// PostTag files a post under a tag
+type PostTag struct {
+	PostID *string `json:"postId" gorm:"primaryKey"`
+	Post   Post    `json:"-" gorm:"foreignKey:PostID"`
+	TagID  uint    `json:"tagId" gorm:"primaryKey;index"`
+	Tag    Tag     `json:"-" gorm:"foreignKey:TagID"`
+}
+
+// TagRef is a tag as read from a Sanity post document
+type TagRef struct {
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
+
+func MigrateTags(db *gorm.DB) error {
+	return db.AutoMigrate(&Tag{}, &PostTag{})
+}
@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"fmt"
+	"log"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"thedefiant.io/analytics/models"
+	"thedefiant.io/analytics/services/analytics"
+	"thedefiant.io/analytics/utils"
+)
+
+type AudienceRepository struct {
+	DB        *gorm.DB
+	Analytics *analytics.Client
+}
+
+// AudienceTotals is the traffic from one country or device category over a period
+type AudienceTotals struct {
+	Name     string `json:"name"`
+	Views    int64  `json:"views"`
+	Sessions int64  `json:"sessions"`
+	Users    int64  `json:"users"`
+}
+
+// AudienceBreakdown is the country and device mix of a set of posts
+type AudienceBreakdown struct {
+	Countries []AudienceTotals `json:"countries"`
+	Devices   []AudienceTotals `json:"devices"`
+}
+
+func NewAudienceRepository(db *gorm.DB, analyticsClient *analytics.Client) *AudienceRepository {
+	return &AudienceRepository{
+		DB:        db,
+		Analytics: analyticsClient,
+	}
+}
+
+// UpdateAudience fetches the country and device breakdown of every published post's
+// traffic on the given date and stores it in post_audiences
+func (r *AudienceRepository) UpdateAudience(date string) ([]models.PostAudience, error) {
+	var posts []models.Post
+	err := r.DB.Where("DATE(published_at) <= ?", date).Where("slug is not NULL").Find(&posts).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch posts for %s: %w", date, err)
+	}
+	if len(posts) == 0 {
+		log.Printf("No posts found for %s", date)
+		return nil, nil
+	}
+
+	paths, err := loadPostPaths(r.DB, r.Analytics.NormalizePath, posts)
+	if err != nil {
+		return nil, err
+	}
+
+	reportRows, metadata, err := r.Analytics.RunReport(analytics.ReportQuery{
+		StartDate:  date,
+		EndDate:    date,
+		Dimensions: []string{"date", "pagePath", "country", "deviceCategory"},
+		Metrics:    []string{"screenPageViews", "sessions", "totalUsers"},
+		Paths:      paths.paths,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audience report: %w", err)
+	}
+	if metadata.Partial() {
+		log.Printf("Partial GA audience data for %s: %+v", date, metadata)
+	}
+
+	// Several paths of the same post fold into one row per country and device
+	type audienceKey struct {
+		postID, date, country, device string
+	}
+	rowIndex := make(map[audienceKey]int)
+	var rows []models.PostAudience
+	for _, row := range reportRows {
+		postID, ok := paths.postByPath[row.Dimensions["pagePath"]]
+		if !ok {
+			continue
+		}
+		rowDate, err := row.Date()
+		if err != nil {
+			return nil, err
+		}
+		key := audienceKey{*postID, rowDate, row.Dimensions["country"], row.Dimensions["deviceCategory"]}
+		if i, ok := rowIndex[key]; ok {
+			rows[i].Views += row.Int("screenPageViews")
+			rows[i].Sessions += row.Int("sessions")
+			rows[i].Users += row.Int("totalUsers")
+			continue
+		}
+		day, _ := utils.ParseDate(rowDate)
+		rowIndex[key] = len(rows)
+		rows = append(rows, models.PostAudience{
+			PostID:         postID,
+			Date:           day,
+			Country:        row.Dimensions["country"],
+			DeviceCategory: row.Dimensions["deviceCategory"],
+			Views:          row.Int("screenPageViews"),
+			Sessions:       row.Int("sessions"),
+			Users:          row.Int("totalUsers"),
+		})
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	err = r.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{
+			{Name: "post_id"}, {Name: "date"}, {Name: "country"}, {Name: "device_category"},
+		},
+		DoUpdates: clause.AssignmentColumns([]string{"views", "sessions", "users", "updated_at"}),
+	}).CreateInBatches(&rows, 500).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to store audience: %w", err)
+	}
+	return rows, nil
+}
+
+// GetPostAudience returns the country and device mix of a single post
+func (r *AudienceRepository) GetPostAudience(postID, from, to string) (*AudienceBreakdown, error) {
+	return r.getAudience(from, to, "1", func(db *gorm.DB) *gorm.DB {
+		return db.Where("posts.id = ?", postID)
+	})
+}
+
+// GetAuthorAudience returns the country and device mix of every post the author wrote or
+// co-wrote, with co-written posts credited according to AuthorCreditMode
+func (r *AudienceRepository) GetAuthorAudience(authorID, from, to string) (*AudienceBreakdown, error) {
+	return r.getAudience(from, to, "credits.credit", func(db *gorm.DB) *gorm.DB {
+		return db.Joins("JOIN (?) credits ON credits.post_id = posts.id AND credits.author_id = ?", postAuthorCredits(r.DB), authorID)
+	})
+}
+
+// GetCategoryAudience returns the country and device mix of a main category, optionally
+// narrowed down to one of its subcategories
+func (r *AudienceRepository) GetCategoryAudience(mainCategory, subCategory, from, to string) (*AudienceBreakdown, error) {
+	return r.getAudience(from, to, "1", func(db *gorm.DB) *gorm.DB {
+		db = db.Where("posts.main_category = ?", mainCategory)
+		if subCategory != "" {
+			db = db.Where("posts.sub_category = ?", subCategory)
+		}
+		return db
+	})
+}
+
+// getAudience sums post_audiences over the posts selected by scope, multiplying each
+// post's numbers by the weight expression
+func (r *AudienceRepository) getAudience(from, to string, weight string, scope func(*gorm.DB) *gorm.DB) (*AudienceBreakdown, error) {
+	breakdown := &AudienceBreakdown{}
+	groups := []struct {
+		column string
+		totals *[]AudienceTotals
+	}{
+		{"country", &breakdown.Countries},
+		{"device_category", &breakdown.Devices},
+	}
+
+	sum := func(column string) string {
+		return fmt.Sprintf("ROUND(SUM(post_audiences.%s * %s))::bigint AS %s", column, weight, column)
+	}
+	for _, group := range groups {
+		query := r.DB.Model(&models.PostAudience{}).
+			Select(group.column+" AS name, "+sum("views")+", "+sum("sessions")+", "+sum("users")).
+			Joins("JOIN posts ON posts.id = post_audiences.post_id").
+			Where("post_audiences.date BETWEEN ? AND ?", from, to).
+			Where("posts.deleted_at IS NULL")
+		err := scope(query).
+			Group(group.column).
+			Order("views desc").
+			Scan(group.totals).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch audience by %s: %w", group.column, err)
+		}
+	}
+	return breakdown, nil
+}
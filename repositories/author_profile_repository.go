@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"thedefiant.io/analytics/models"
+	"thedefiant.io/analytics/utils"
+)
+
+// ErrAuthorNotFound is returned when no author has the requested ID
+var ErrAuthorNotFound = errors.New("author not found")
+
+// profileMonths is the length of the monthly views series of an author profile
+const profileMonths = 12
+
+// AuthorStanding is an author's numbers over one period and their rank among all authors
+type AuthorStanding struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Views int64  `json:"views"`
+	// Posts is the number of posts the author published in the period
+	Posts int64 `json:"posts"`
+	// Rank is the author's position by views, 1 being the most viewed
+	Rank    int `json:"rank"`
+	Authors int `json:"authors"`
+}
+
+// AuthorChange compares the selected period with the one before it. ViewsPercent is nil
+// when the previous period had no views. A positive Rank means the author moved up.
+type AuthorChange struct {
+	Views        int64    `json:"views"`
+	ViewsPercent *float64 `json:"viewsPercent"`
+	Posts        int64    `json:"posts"`
+	Rank         int      `json:"rank"`
+}
+
+// AuthorCategoryViews is the share of an author's views that came from one main category
+type AuthorCategoryViews struct {
+	MainCategory string `json:"mainCategory"`
+	// Posts is the number of the author's posts in the category that had views
+	Posts int64   `json:"posts"`
+	Views int64   `json:"views"`
+	Share float64 `json:"share"`
+}
+
+// AuthorProfile is everything the author page shows for a selected period
+type AuthorProfile struct {
+	models.Author
+	Current  AuthorStanding `json:"current"`
+	Previous AuthorStanding `json:"previous"`
+	Change   AuthorChange   `json:"change"`
+	// Posts are the author's posts published in the period, with their view windows
+	Posts []models.Post `json:"posts"`
+	// Monthly is the author's views in each of the last profileMonths calendar months
+	Monthly []AuthorPeriodViews `json:"monthly"`
+	// Categories splits the author's views in the period by main category
+	Categories []AuthorCategoryViews `json:"categories"`
+}
+
+// GetAuthorProfile builds an author's profile for the period from..to, comparing it with
+// the period of the same length right before it. Views of co-written posts are credited
+// according to AuthorCreditMode.
+func (r *AuthorRepository) GetAuthorProfile(id, from, to string) (*AuthorProfile, error) {
+	var author models.Author
+	err := r.DB.Where("id = ?", id).First(&author).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAuthorNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch author by ID: %w", err)
+	}
+
+	start, err := utils.ParseDate(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start date %s: %w", from, err)
+	}
+	end, err := utils.ParseDate(to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end date %s: %w", to, err)
+	}
+	days := int(end.Sub(start).Hours()/24) + 1
+	prevEnd := start.AddDate(0, 0, -1)
+	prevStart := prevEnd.AddDate(0, 0, -(days - 1))
+
+	profile := &AuthorProfile{Author: author}
+	if profile.Current, err = r.getAuthorStanding(id, from, to); err != nil {
+		return nil, err
+	}
+	if profile.Previous, err = r.getAuthorStanding(id, utils.FormatDate(prevStart), utils.FormatDate(prevEnd)); err != nil {
+		return nil, err
+	}
+	profile.Change = AuthorChange{
+		Views: profile.Current.Views - profile.Previous.Views,
+		Posts: profile.Current.Posts - profile.Previous.Posts,
+		Rank:  profile.Previous.Rank - profile.Current.Rank,
+	}
+	if profile.Previous.Views > 0 {
+		percent := float64(profile.Change.Views) / float64(profile.Previous.Views) * 100
+		profile.Change.ViewsPercent = &percent
+	}
+
+	profile.Posts = make([]models.Post, 0)
+	err = r.DB.Joins("JOIN post_authors pa ON pa.post_id = posts.id AND pa.author_id = ?", id).
+		Where("DATE(posts.published_at) BETWEEN ? AND ?", from, to).
+		Order("posts.published_at desc").
+		Find(&profile.Posts).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch posts of author %s: %w", id, err)
+	}
+
+	monthsFrom := time.Date(end.Year(), end.Month()-profileMonths+1, 1, 0, 0, 0, 0, time.UTC)
+	rollups, err := r.GetAuthorRollups(AuthorPeriodMonthly, utils.FormatDate(monthsFrom), to)
+	if err != nil {
+		return nil, err
+	}
+	for _, rollup := range rollups {
+		if *rollup.ID == id {
+			profile.Monthly = rollup.Periods
+			break
+		}
+	}
+
+	if profile.Categories, err = r.getAuthorCategories(id, from, to); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// getAuthorStanding returns an author's views, published posts and rank over a period
+func (r *AuthorRepository) getAuthorStanding(id, from, to string) (AuthorStanding, error) {
+	standing := AuthorStanding{From: from, To: to}
+
+	// A monthly rollup over the period totals each author's views for exactly from..to
+	rollups, err := r.GetAuthorRollups(AuthorPeriodMonthly, from, to)
+	if err != nil {
+		return standing, err
+	}
+	standing.Authors = len(rollups)
+	for i, rollup := range rollups {
+		if *rollup.ID == id {
+			standing.Views = rollup.Views
+			standing.Rank = i + 1
+			break
+		}
+	}
+
+	err = r.DB.Model(&models.Post{}).
+		Joins("JOIN post_authors pa ON pa.post_id = posts.id AND pa.author_id = ?", id).
+		Where("DATE(posts.published_at) BETWEEN ? AND ?", from, to).
+		Count(&standing.Posts).Error
+	if err != nil {
+		return standing, fmt.Errorf("failed to count posts of author %s: %w", id, err)
+	}
+	return standing, nil
+}
+
+// getAuthorCategories splits an author's credited views over a period by main category
+func (r *AuthorRepository) getAuthorCategories(id, from, to string) ([]AuthorCategoryViews, error) {
+	categories := make([]AuthorCategoryViews, 0)
+	err := r.DB.Table("post_daily_views d").
+		Select("posts.main_category, COUNT(DISTINCT posts.id) AS posts, ROUND(SUM(d.views * credits.credit))::bigint AS views").
+		Joins("JOIN posts ON posts.id = d.post_id AND posts.deleted_at IS NULL").
+		Joins("JOIN (?) credits ON credits.post_id = d.post_id AND credits.author_id = ?", postAuthorCredits(r.DB), id).
+		Where("d.date BETWEEN ? AND ?", from, to).
+		Group("posts.main_category").
+		Order("views desc").
+		Scan(&categories).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch category mix of author %s: %w", id, err)
+	}
+
+	var total int64
+	for _, category := range categories {
+		total += category.Views
+	}
+	for i := range categories {
+		if total > 0 {
+			categories[i].Share = float64(categories[i].Views) / float64(total)
+		}
+	}
+	return categories, nil
+}
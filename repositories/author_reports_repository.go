@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"thedefiant.io/analytics/models"
+	"thedefiant.io/analytics/utils"
+)
+
+// monthLayout is the format of the month arguments of the author report functions
+const monthLayout = "2006-01"
+
+// ErrInvalidMonth is wrapped by author report errors caused by a bad month argument
+var ErrInvalidMonth = errors.New("invalid month")
+
+// parseMonth returns the first and last day of a "2006-01" month
+func parseMonth(month string) (time.Time, time.Time, error) {
+	start, err := time.Parse(monthLayout, month)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w %q, expected YYYY-MM: %v", ErrInvalidMonth, month, err)
+	}
+	return start, start.AddDate(0, 1, -1), nil
+}
+
+// monthlyAuthorViewRows turns the rollups of one month into author_views rows, keeping
+// the rollups without views as zero rows
+func monthlyAuthorViewRows(rollups []AuthorRollup, start, end time.Time) []models.AuthorViews {
+	rows := make([]models.AuthorViews, 0, len(rollups))
+	for _, rollup := range rollups {
+		rows = append(rows, models.AuthorViews{
+			AuthorId:    rollup.ID,
+			Views:       rollup.Views,
+			PeriodStart: &start,
+			PeriodEnd:   &end,
+		})
+	}
+	return rows
+}
+
+// authorViewsUpsert makes storing a month's rows again replace their views
+var authorViewsUpsert = clause.OnConflict{
+	Columns:   []clause.Column{{Name: "author_id"}, {Name: "period_start"}, {Name: "period_end"}},
+	DoUpdates: clause.AssignmentColumns([]string{"views", "updated_at"}),
+}
+
+// StoreMonthlyAuthorViews stores every author's views for a finished calendar month
+// ("2006-01"), replacing the rows of an earlier run for the same month. Authors with no
+// views that month get a zero row so every report lists the whole team. Credited authors
+// missing from the authors table are stored as placeholders first.
+func (r *AuthorRepository) StoreMonthlyAuthorViews(month string) ([]models.AuthorViews, error) {
+	start, end, err := parseMonth(month)
+	if err != nil {
+		return nil, err
+	}
+	from, to := utils.FormatDate(start), utils.FormatDate(end)
+	if to > utils.GetYesterdayDate() {
+		return nil, fmt.Errorf("%w: %s has not finished yet", ErrInvalidMonth, month)
+	}
+	covered, err := dailyViewsCover(r.DB, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if !covered {
+		return nil, fmt.Errorf("daily views are not stored for all of %s, backfill them first", month)
+	}
+
+	rollups, err := r.GetAuthorRollups(AuthorPeriodMonthly, from, to)
+	if err != nil {
+		return nil, err
+	}
+	rows := monthlyAuthorViewRows(rollups, start, end)
+	if len(rows) == 0 {
+		return rows, nil
+	}
+
+	ids := make([]string, 0, len(rollups))
+	for _, rollup := range rollups {
+		ids = append(ids, *rollup.ID)
+	}
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := ensureAuthors(tx, ids); err != nil {
+			return err
+		}
+		return tx.Clauses(authorViewsUpsert).CreateInBatches(&rows, 500).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store author views for %s: %w", month, err)
+	}
+	return rows, nil
+}
+
+// BackfillMonthlyAuthorViews stores the monthly author views of every month from one
+// "2006-01" month to another, inclusive. Months already stored are recomputed.
+func (r *AuthorRepository) BackfillMonthlyAuthorViews(from, to string) (int, error) {
+	start, _, err := parseMonth(from)
+	if err != nil {
+		return 0, err
+	}
+	end, _, err := parseMonth(to)
+	if err != nil {
+		return 0, err
+	}
+	if start.After(end) {
+		return 0, fmt.Errorf("%w range: %s to %s", ErrInvalidMonth, from, to)
+	}
+
+	months := 0
+	for month := start; !month.After(end); month = month.AddDate(0, 1, 0) {
+		if _, err := r.StoreMonthlyAuthorViews(month.Format(monthLayout)); err != nil {
+			return months, err
+		}
+		log.Printf("Stored author views for %s", month.Format(monthLayout))
+		months++
+	}
+	return months, nil
+}
+
+// GetMonthlyAuthorViews returns the stored monthly author views from one "2006-01" month to
+// another, optionally for a single author, ordered by month and then by views
+func (r *AuthorRepository) GetMonthlyAuthorViews(from, to, authorID string) ([]models.AuthorViews, error) {
+	start, _, err := parseMonth(from)
+	if err != nil {
+		return nil, err
+	}
+	_, end, err := parseMonth(to)
+	if err != nil {
+		return nil, err
+	}
+
+	query := r.DB.Where("period_start >= ? AND period_end <= ?", utils.FormatDate(start), utils.FormatDate(end))
+	if authorID != "" {
+		query = query.Where("author_id = ?", authorID)
+	}
+	views := make([]models.AuthorViews, 0)
+	if err := query.Order("period_start, views desc").Find(&views).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch author views: %w", err)
+	}
+	return views, nil
+}
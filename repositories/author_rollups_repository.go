@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"thedefiant.io/analytics/models"
+	"thedefiant.io/analytics/utils"
+)
+
+// Author rollup periods
+const (
+	AuthorPeriodDaily     = "daily"
+	AuthorPeriodWeekly    = "weekly"
+	AuthorPeriodMonthly   = "monthly"
+	AuthorPeriodRolling30 = "rolling30"
+)
+
+// ErrInvalidAuthorPeriod is returned for an unknown rollup period
+var ErrInvalidAuthorPeriod = errors.New("invalid author period")
+
+// AuthorPeriodViews is the views credited to an author in one period. Periods are clipped
+// to the requested range, so the first and last ones may be shorter than a full period.
+type AuthorPeriodViews struct {
+	PeriodStart string `json:"periodStart"`
+	PeriodEnd   string `json:"periodEnd"`
+	Views       int64  `json:"views"`
+}
+
+// AuthorRollup is an author's views over a range, in total and per period
+type AuthorRollup struct {
+	ID      *string             `json:"id"`
+	Name    *string             `json:"name"`
+	Views   int64               `json:"views"`
+	Periods []AuthorPeriodViews `json:"periods"`
+}
+
+// authorBucket is one period of a rollup; start is the untruncated period start that
+// post_daily_views rows are grouped by
+type authorBucket struct {
+	start time.Time
+	AuthorPeriodViews
+}
+
+// authorBuckets splits from..to into the periods of a rollup. Weeks start on Monday, like
+// Postgres' date_trunc('week'). A rolling 30-day rollup is the single period ending on to.
+func authorBuckets(period string, from, to time.Time) ([]authorBucket, string, error) {
+	var expr string
+	var next func(time.Time) time.Time
+	start := from
+	switch period {
+	case AuthorPeriodDaily:
+		expr = "d.date"
+		next = func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }
+	case AuthorPeriodWeekly:
+		expr = "date_trunc('week', d.date)::date"
+		start = from.AddDate(0, 0, -((int(from.Weekday()) + 6) % 7))
+		next = func(t time.Time) time.Time { return t.AddDate(0, 0, 7) }
+	case AuthorPeriodMonthly:
+		expr = "date_trunc('month', d.date)::date"
+		start = time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
+		next = func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }
+	case AuthorPeriodRolling30:
+		from = to.AddDate(0, 0, -29)
+		start = from
+		expr = fmt.Sprintf("'%s'::date", utils.FormatDate(from))
+		next = func(t time.Time) time.Time { return t.AddDate(0, 0, 30) }
+	default:
+		return nil, "", fmt.Errorf("%w: %s", ErrInvalidAuthorPeriod, period)
+	}
+
+	var buckets []authorBucket
+	for ; !start.After(to); start = next(start) {
+		periodStart, periodEnd := start, next(start).AddDate(0, 0, -1)
+		if periodStart.Before(from) {
+			periodStart = from
+		}
+		if periodEnd.After(to) {
+			periodEnd = to
+		}
+		buckets = append(buckets, authorBucket{
+			start: start,
+			AuthorPeriodViews: AuthorPeriodViews{
+				PeriodStart: utils.FormatDate(periodStart),
+				PeriodEnd:   utils.FormatDate(periodEnd),
+			},
+		})
+	}
+	return buckets, expr, nil
+}
+
+// GetAuthorRollups sums the stored daily views of every author's posts per period between
+// two dates, crediting co-written posts according to AuthorCreditMode. Nothing is fetched
+// from GA, so the numbers always add up to the post numbers. Every author gets every
+// period, with zero views where they had none. Authors deleted in Sanity are only listed
+// when their posts had views. For AuthorPeriodRolling30 from is ignored.
+func (r *AuthorRepository) GetAuthorRollups(period, from, to string) ([]AuthorRollup, error) {
+	start, err := utils.ParseDate(from)
+	if err != nil && period != AuthorPeriodRolling30 {
+		return nil, fmt.Errorf("invalid start date %s: %w", from, err)
+	}
+	end, err := utils.ParseDate(to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end date %s: %w", to, err)
+	}
+	buckets, bucketExpr, err := authorBuckets(period, start, end)
+	if err != nil {
+		return nil, err
+	}
+	first, last := buckets[0].PeriodStart, buckets[len(buckets)-1].PeriodEnd
+
+	var rows []struct {
+		AuthorID    string
+		PeriodStart time.Time
+		Views       int64
+	}
+	err = r.DB.Table("post_daily_views d").
+		Select("credits.author_id, "+bucketExpr+" AS period_start, ROUND(SUM(d.views * credits.credit))::bigint AS views").
+		Joins("JOIN posts ON posts.id = d.post_id AND posts.deleted_at IS NULL").
+		Joins("JOIN (?) credits ON credits.post_id = d.post_id", postAuthorCredits(r.DB)).
+		Where("d.date BETWEEN ? AND ?", first, last).
+		Group("credits.author_id, period_start").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate author views: %w", err)
+	}
+
+	var authors []models.Author
+	if err := r.DB.Unscoped().Find(&authors).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch authors: %w", err)
+	}
+	names := make(map[string]*string, len(authors))
+
+	rollups := make(map[string]*AuthorRollup, len(authors))
+	var ordered []*AuthorRollup
+	rollupFor := func(id string, name *string) *AuthorRollup {
+		if rollup, ok := rollups[id]; ok {
+			return rollup
+		}
+		authorID := id
+		rollup := &AuthorRollup{ID: &authorID, Name: name, Periods: make([]AuthorPeriodViews, len(buckets))}
+		for i, bucket := range buckets {
+			rollup.Periods[i] = bucket.AuthorPeriodViews
+		}
+		rollups[id] = rollup
+		ordered = append(ordered, rollup)
+		return rollup
+	}
+	for _, author := range authors {
+		names[*author.ID] = author.Name
+		if !author.DeletedAt.Valid {
+			rollupFor(*author.ID, author.Name)
+		}
+	}
+
+	bucketIndex := make(map[string]int, len(buckets))
+	for i, bucket := range buckets {
+		bucketIndex[utils.FormatDate(bucket.start)] = i
+	}
+	for _, row := range rows {
+		i, ok := bucketIndex[utils.FormatDate(row.PeriodStart)]
+		if !ok {
+			continue
+		}
+		// Authors missing from the authors table still get credited, without a name
+		rollup := rollupFor(row.AuthorID, names[row.AuthorID])
+		rollup.Periods[i].Views += row.Views
+		rollup.Views += row.Views
+	}
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Views > ordered[j].Views
+	})
+	result := make([]AuthorRollup, len(ordered))
+	for i, rollup := range ordered {
+		result[i] = *rollup
+	}
+	return result, nil
+}
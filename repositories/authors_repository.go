@@ -3,9 +3,9 @@ package repository
 import (
 	"fmt"
 	"log"
-	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"thedefiant.io/analytics/models"
 	"thedefiant.io/analytics/services/analytics"
 	"thedefiant.io/analytics/services/sanity"
@@ -18,24 +18,6 @@ type AuthorRepository struct {
 	Analytics *analytics.Client
 }
 
-type AuthorWithViews struct {
-	models.Author
-	Views int64
-	CreatedAt time.Time
-}
-
-type AuthorsResponse struct {
-	models.Author
-	Views []AuthorView `json:"views"`
-}
-
-// AuthorView represents a single view entry for an author
-type AuthorView struct {
-	View      int64     `json:"view"`
-	CreatedAt time.Time `json:"createdAt"`
-}
-
-
 func NewAuthorRepository(db *gorm.DB, sanityClient *sanity.Client, analyticsClient *analytics.Client) *AuthorRepository {
 	return &AuthorRepository{
 		DB:     db,
@@ -73,156 +55,90 @@ func (r *AuthorRepository) CreateAuthor() ([]models.Author, error) {
 	return authors, nil
 }
 
-func (r *AuthorRepository) GetAuthorsFromDatabase() ([]AuthorsResponse, error) {
-	var authorsWithViews []AuthorWithViews
-
-	// Fetch all authors with their views
-	err := r.DB.Model(&models.Author{}).
-		Select("authors.*, author_views.views, author_views.created_at").
-		Joins("LEFT JOIN author_views ON authors.id = author_views.author_id").
-		Order("authors.id, author_views.created_at DESC").
-		Where("author_views.views IS NOT NULL").
-		Find(&authorsWithViews).Error
 
+func (r *AuthorRepository) GetAuthorByID(id string) (*models.Author, error) {
+	var author models.Author
+	err := r.DB.Where("id = ?", id).First(&author).Error
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch authors with views: %w", err)
-	}
-
-	// map author views into author response struct appending views if author exists
-	authors := make([]AuthorsResponse, 0)
-	for _, author := range authorsWithViews {
-		if len(authors) == 0 || *authors[len(authors)-1].ID != *author.ID {
-			authors = append(authors, AuthorsResponse{
-				Author: models.Author{
-					ID: author.ID,
-					Name: author.Name,
-					CreatedAt: author.CreatedAt,
-				},
-				Views: []AuthorView{
-					{
-						View: 	author.Views,
-						CreatedAt: author.CreatedAt,
-					},
-				},
-			})
-	} else {
-		authors[len(authors)-1].Views = append(authors[len(authors)-1].Views, AuthorView{
-			View: author.Views,
-			CreatedAt: author.CreatedAt,
-		})
+		return nil, fmt.Errorf("failed to fetch author by ID: %w", err)
 	}
+	return &author, nil
 }
 
-	
-
-	return authors, nil
+func (r *AuthorRepository) UpdateAuthor(author *models.Author) error {
+	return r.DB.Save(author).Error
 }
 
-
-func (r *AuthorRepository) GetAuthorByID(id string) (*models.Author, error) {
-	var author models.Author
-	err := r.DB.Where("id = ?", id).First(&author).Error
+func (r *AuthorRepository) DeleteAuthor(id string) error {
+	return r.DB.Delete(&models.Author{}, "id = ?", id).Error
+}
+// UpsertAuthor inserts an author coming from Sanity or updates the stored name, restoring
+// the author if they had been deleted
+func (r *AuthorRepository) UpsertAuthor(author *models.Author) error {
+	err := r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "deleted_at"}),
+	}).Create(author).Error
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch author by ID: %w", err)
+		return fmt.Errorf("failed to upsert author: %w", err)
 	}
-	return &author, nil
+	return nil
 }
 
-func (r *AuthorRepository) GetSanityPostsByAuthorCurrentMonth() ([]models.OutputAuthor, error) {
-	thirtyDaysAgo := utils.GetDateNDaysAgo(30)
-	today := time.Now().Format("2006-01-02")
-	query := `*[_type == 'author' && count(
-		*[_type in ['blog', 'sponsor'] 
-		&& references(^._id) 
-		&& publishedAt > $from
-		&& publishedAt < $to 
-		&& defined(mainCategory) 
-		&& defined(subCategory)
-		]) > 0
-	]{
-		_id,
-		name,
-		'postsSlugs': *[_type in ['blog', 'sponsor'] 
-		&& references(^._id) 
-		&& publishedAt > $from
-		&& publishedAt < $to 
-		&& defined(mainCategory) 
-		&& defined(subCategory)
-		]{
-			'slug': slug.current,
-			'mainCategory': mainCategory->slug.current,
-			'subCategory': subCategory->slug.current
+// ensureAuthors stores a nameless placeholder for every id missing from the authors table,
+// deleted authors included, so rows referencing them satisfy their foreign key. Syncing
+// the author from Sanity fills in the name later.
+func ensureAuthors(tx *gorm.DB, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	var known []string
+	if err := tx.Unscoped().Model(&models.Author{}).Where("id IN ?", ids).Pluck("id", &known).Error; err != nil {
+		return fmt.Errorf("failed to fetch authors: %w", err)
+	}
+	stored := make(map[string]bool, len(known))
+	for _, id := range known {
+		stored[id] = true
+	}
+	var missing []models.Author
+	for i := range ids {
+		if !stored[ids[i]] {
+			stored[ids[i]] = true
+			missing = append(missing, models.Author{ID: &ids[i]})
 		}
-	}`
-	
-	params := map[string]interface{}{
-		"from": thirtyDaysAgo,
-		"to":   today,
 	}
+	if len(missing) == 0 {
+		return nil
+	}
+	log.Printf("Storing %d authors missing from the authors table as placeholders", len(missing))
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&missing).Error; err != nil {
+		return fmt.Errorf("failed to store placeholder authors: %w", err)
+	}
+	return nil
+}
 
-	result, err := r.Sanity.Query(query, params)
+// SyncAuthor re-reads an author from Sanity and stores them. An author that no longer
+// exists in Sanity is soft-deleted, and nil is returned for them.
+func (r *AuthorRepository) SyncAuthor(id string) (*models.Author, error) {
+	result, err := r.Sanity.Query(`*[_type == 'author' && _id == $id][0]{'id':_id,name}`, map[string]interface{}{
+		"id": id,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to query Sanity: %w", err)
 	}
 
-	var inputAuthors []models.InputAuthor
-	if err := result.Unmarshal(&inputAuthors); err != nil {
+	var author *models.Author
+	if err := result.Unmarshal(&author); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal Sanity result: %w", err)
 	}
-
-	outputAuthors := make([]models.OutputAuthor, len(inputAuthors))
-	for i, author := range inputAuthors {
-		slugs := make([]string, len(author.PostsSlugs))
-		for j, post := range author.PostsSlugs {
-			slugs[j] = "/" + post.MainCategory + "/" + post.SubCategory + "/" + post.Slug 
-		}
-		outputAuthors[i] = models.OutputAuthor{
-			ID:   author.ID,
-			Slugs: slugs,
+	if author == nil {
+		if err := r.DeleteAuthor(id); err != nil {
+			return nil, fmt.Errorf("failed to delete author %s: %w", id, err)
 		}
+		return nil, nil
 	}
-
-	return outputAuthors, nil
-}
-
-func (r *AuthorRepository) UpdateAnalyticsViews()([]models.AuthorViews, error){
-	authorsAndSlugs, err := r.GetSanityPostsByAuthorCurrentMonth()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get authors and slugs: %w", err)
-	}
-	authorViews:= make([]models.AnalyticsReponse, len(authorsAndSlugs))
-	for i, author := range authorsAndSlugs {
-		
-		authorViews[i].ID = author.ID
-		
-		pageViews, err := r.Analytics.GetPageViews("last30days", author.Slugs)
-		
-		if err != nil {
-			return nil, fmt.Errorf("failed to get page views: %w", err)
-		}
-		for _, slug := range author.Slugs {
-			authorViews[i].Views += pageViews[slug]
-		}	
-	}
-	authorViewsDB := make([]models.AuthorViews, len(authorViews))
-	for i, authorView := range authorViews {
-		authorViewsDB[i] = models.AuthorViews{
-			AuthorId: &authorView.ID,
-			Views: authorView.Views,
-			CreatedAt: time.Now(),
-		}
-		err := r.DB.Create(&authorViewsDB[i]).Error
-		if err != nil {
-			log.Printf("Error creating author views: %v", err)
-		}
+	if err := r.UpsertAuthor(author); err != nil {
+		return nil, err
 	}
-	return authorViewsDB, nil
+	return author, nil
 }
-
-func (r *AuthorRepository) UpdateAuthor(author *models.Author) error {
-	return r.DB.Save(author).Error
-}
-
-func (r *AuthorRepository) DeleteAuthor(id string) error {
-	return r.DB.Delete(&models.Author{}, "id = ?", id).Error
-}
\ No newline at end of file
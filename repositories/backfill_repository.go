@@ -0,0 +1,226 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"thedefiant.io/analytics/models"
+	"thedefiant.io/analytics/services/analytics"
+	"thedefiant.io/analytics/utils"
+)
+
+const (
+	// backfillChunkDelay spaces out GA requests so a long backfill doesn't drain the hourly token quota
+	backfillChunkDelay = 2 * time.Second
+	// backfillMaxRetries is how many times a chunk is retried after a quota error
+	backfillMaxRetries = 5
+	// ingestionLockKey is the Postgres advisory lock held while views are ingested from GA
+	ingestionLockKey = 48172001
+)
+
+// ErrBackfillRunning is returned when a backfill is already in progress in any process
+// sharing the database
+var ErrBackfillRunning = errors.New("a backfill is already running")
+
+// lockIngestion takes the advisory lock that makes sure only one backfill, catch-up or
+// nightly ingestion talks to GA at a time, across the server and the CLI. Postgres ties the
+// lock to the connection that took it, so one connection is held until the returned
+// release func is called. When the lock is already held it waits for it if wait is set,
+// and returns ErrBackfillRunning otherwise.
+func (r *PostRepository) lockIngestion(wait bool) (func(), error) {
+	sqlDB, err := r.DB.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database handle: %w", err)
+	}
+	ctx := context.Background()
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database connection: %w", err)
+	}
+	query := "SELECT pg_try_advisory_lock($1)"
+	if wait {
+		query = "SELECT pg_advisory_lock($1) IS NOT NULL"
+	}
+	var locked bool
+	if err := conn.QueryRowContext(ctx, query, ingestionLockKey).Scan(&locked); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to take ingestion lock: %w", err)
+	}
+	if !locked {
+		conn.Close()
+		return nil, ErrBackfillRunning
+	}
+	return func() {
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", ingestionLockKey); err != nil {
+			log.Printf("Failed to release ingestion lock, dropping its connection: %v", err)
+			// A connection still holding the lock must not go back to the pool
+			conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+		}
+		conn.Close()
+	}, nil
+}
+
+// CreateBackfillJob validates the range and returns a job for it. An unfinished job over
+// the same range is reused so a repeated request resumes instead of starting over.
+func (r *PostRepository) CreateBackfillJob(from, to string, chunkDays int) (*models.BackfillJob, error) {
+	if !utils.IsValidDateRange(from, to) {
+		return nil, fmt.Errorf("invalid date range: %s to %s", from, to)
+	}
+	if to > utils.GetYesterdayDate() {
+		return nil, fmt.Errorf("end date %s must be yesterday or earlier", to)
+	}
+	if chunkDays <= 0 {
+		chunkDays = 1
+	}
+	start, _ := utils.ParseDate(from)
+	end, _ := utils.ParseDate(to)
+
+	var job models.BackfillJob
+	err := r.DB.Where("start_date = ? AND end_date = ? AND status <> ?", from, to, models.BackfillStatusCompleted).
+		Order("created_at desc").
+		First(&job).Error
+	if err == nil {
+		if job.ChunkDays != chunkDays {
+			if err := r.DB.Model(&job).Update("chunk_days", chunkDays).Error; err != nil {
+				return nil, fmt.Errorf("failed to update backfill %d chunk size: %w", job.ID, err)
+			}
+		}
+		return &job, nil
+	}
+
+	job = models.BackfillJob{
+		StartDate: start,
+		EndDate:   end,
+		NextDate:  start,
+		ChunkDays: chunkDays,
+		Status:    models.BackfillStatusPending,
+	}
+	if err := r.DB.Create(&job).Error; err != nil {
+		return nil, fmt.Errorf("failed to create backfill job: %w", err)
+	}
+	return &job, nil
+}
+
+// GetBackfillJob returns a backfill job by ID
+func (r *PostRepository) GetBackfillJob(id uint) (*models.BackfillJob, error) {
+	var job models.BackfillJob
+	if err := r.DB.First(&job, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch backfill job: %w", err)
+	}
+	return &job, nil
+}
+
+// GetBackfillJobs returns all backfill jobs, newest first
+func (r *PostRepository) GetBackfillJobs() ([]models.BackfillJob, error) {
+	var jobs []models.BackfillJob
+	if err := r.DB.Order("created_at desc").Find(&jobs).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch backfill jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// RunBackfill walks the job's range in chunks, storing daily views for every post
+// published by the end of each chunk. Progress is saved after each chunk, so running
+// the same job again continues from its NextDate.
+func (r *PostRepository) RunBackfill(job *models.BackfillJob) error {
+	unlock, err := r.lockIngestion(false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return r.runBackfill(job)
+}
+
+// StartBackfill runs the job in the background. It returns ErrBackfillRunning right away,
+// without starting anything, when another backfill is in progress.
+func (r *PostRepository) StartBackfill(job *models.BackfillJob) error {
+	unlock, err := r.lockIngestion(false)
+	if err != nil {
+		return err
+	}
+	go func() {
+		defer unlock()
+		if err := r.runBackfill(job); err != nil {
+			log.Printf("Error running backfill %d: %v", job.ID, err)
+		}
+	}()
+	return nil
+}
+
+// runBackfill does the work of RunBackfill; the caller holds the ingestion lock
+func (r *PostRepository) runBackfill(job *models.BackfillJob) error {
+	if job.Status == models.BackfillStatusCompleted {
+		return nil
+	}
+	r.setBackfillStatus(job, models.BackfillStatusRunning, "")
+
+	for !job.NextDate.After(job.EndDate) {
+		chunkEnd := job.NextDate.AddDate(0, 0, job.ChunkDays-1)
+		if chunkEnd.After(job.EndDate) {
+			chunkEnd = job.EndDate
+		}
+		from, to := utils.FormatDate(job.NextDate), utils.FormatDate(chunkEnd)
+
+		var posts []models.Post
+		err := r.DB.Where("DATE(published_at) <= ?", to).Where("slug is not NULL").Find(&posts).Error
+		if err != nil {
+			r.setBackfillStatus(job, models.BackfillStatusFailed, err.Error())
+			return fmt.Errorf("failed to fetch posts for %s: %w", to, err)
+		}
+
+		if len(posts) > 0 {
+			if err := r.backfillChunk(from, to, posts); err != nil {
+				r.setBackfillStatus(job, models.BackfillStatusFailed, err.Error())
+				return err
+			}
+		}
+		log.Printf("Backfill %d: stored views from %s to %s for %d posts", job.ID, from, to, len(posts))
+
+		job.NextDate = chunkEnd.AddDate(0, 0, 1)
+		if err := r.DB.Model(job).Update("next_date", job.NextDate).Error; err != nil {
+			log.Printf("Error saving backfill %d progress: %v", job.ID, err)
+		}
+		time.Sleep(backfillChunkDelay)
+	}
+
+	// Windows of older posts may now be fully covered by daily data
+	for _, rangeType := range utils.GetRangeTypes() {
+		if err := r.refreshWindowViews(rangeType, ""); err != nil {
+			r.setBackfillStatus(job, models.BackfillStatusFailed, err.Error())
+			return err
+		}
+	}
+
+	r.setBackfillStatus(job, models.BackfillStatusCompleted, "")
+	return nil
+}
+
+// backfillChunk stores one chunk, backing off and retrying when GA reports exhausted quota
+func (r *PostRepository) backfillChunk(from, to string, posts []models.Post) error {
+	wait := time.Minute
+	for attempt := 0; ; attempt++ {
+		_, err := r.storeDailyViews(from, to, posts)
+		if err == nil {
+			return nil
+		}
+		if !analytics.IsQuotaError(err) || attempt >= backfillMaxRetries {
+			return fmt.Errorf("failed to backfill %s to %s: %w", from, to, err)
+		}
+		log.Printf("GA quota exhausted while backfilling %s to %s, retrying in %s", from, to, wait)
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+func (r *PostRepository) setBackfillStatus(job *models.BackfillJob, status, errMsg string) {
+	job.Status = status
+	job.Error = errMsg
+	err := r.DB.Model(job).Updates(map[string]interface{}{"status": status, "error": errMsg}).Error
+	if err != nil {
+		log.Printf("Error updating backfill %d status: %v", job.ID, err)
+	}
+}
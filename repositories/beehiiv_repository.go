@@ -2,11 +2,13 @@
 package repository
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"thedefiant.io/analytics/models"
 	"thedefiant.io/analytics/services/beehiiv"
 )
@@ -48,74 +50,111 @@ func NewBeehiivMetricsRepository(db *gorm.DB, client *beehiiv.Client) *BeehiivMe
 	}
 }
 
-func (r *BeehiivMetricsRepository) UpdatePostMetrics() error {
-	var page = 1
-	for {
-		posts, err := r.Client.GetPosts(page)
+// UpdatePostMetrics stores a new snapshot of every post's stats. All snapshots of a run
+// share the same capture time, so running twice at once cannot store duplicates. Pages
+// are walked up to total_pages; when Beehiiv leaves it out the sync stops at the first
+// page that isn't full. A page that still fails after the client's retries is skipped,
+// if the page count is known, and reported once the other pages are stored.
+func (r *BeehiivMetricsRepository) UpdatePostMetrics(ctx context.Context) error {
+	capturedAt := time.Now().UTC().Truncate(time.Second)
+	var failedPages []int
+	var lastErr error
+	totalPages := 0
+	for page := 1; totalPages == 0 || page <= totalPages; page++ {
+		posts, err := r.Client.GetPosts(ctx, page)
 		if err != nil {
-			return fmt.Errorf("failed to get posts: %w", err)
+			// Without a page count we can't tell whether later pages exist
+			if totalPages == 0 || ctx.Err() != nil {
+				return fmt.Errorf("failed to get posts: %w", err)
+			}
+			log.Printf("Skipping Beehiiv posts page %d: %v", page, err)
+			failedPages = append(failedPages, page)
+			lastErr = err
+			continue
 		}
-		
-		if len(posts.Data) == 0 {
-			break
+		if posts.TotalPages > 0 {
+			totalPages = posts.TotalPages
 		}
 
 		for _, post := range posts.Data {
-			// Calculate rates
-			emailOpenRate := float64(0)
-			if post.Stats.Email.Delivered > 0 {
-				emailOpenRate = float64(post.Stats.Email.UniqueOpens) / float64(post.Stats.Email.Delivered) * 100
-			}
-			
-			emailClickRate := float64(0)
-			if post.Stats.Email.UniqueOpens > 0 {
-				emailClickRate = float64(post.Stats.Email.UniqueClicks) / float64(post.Stats.Email.UniqueOpens) * 100
-			}
-			
-			webClickRate := float64(0)
-			if post.Stats.Web.Views > 0 {
-				webClickRate = float64(post.Stats.Web.Clicks) / float64(post.Stats.Web.Views) * 100
-			}
-
-			metrics := models.BeehiivPostMetrics{
-				PostID:            post.ID,
-				Title:             post.Title,
-				Slug:              post.Slug,
-				PublishDate:       time.Unix(post.PublishDate, 0),
-				
-				EmailRecipients:   post.Stats.Email.Recipients,
-				EmailDelivered:    post.Stats.Email.Delivered,
-				EmailOpens:        post.Stats.Email.Opens,
-				EmailUniqueOpens:  post.Stats.Email.UniqueOpens,
-				EmailClicks:       post.Stats.Email.Clicks,
-				EmailUniqueClicks: post.Stats.Email.UniqueClicks,
-				EmailOpenRate:     emailOpenRate,
-				EmailClickRate:    emailClickRate,
-				
-				WebViews:         post.Stats.Web.Views,
-				WebClicks:        post.Stats.Web.Clicks,
-				WebClickRate:     webClickRate,
-				
-				TotalEngagements: post.Stats.Email.UniqueOpens + post.Stats.Email.UniqueClicks + post.Stats.Web.Views + post.Stats.Web.Clicks,
-				
-				CreatedAt:        time.Now(),
-			}
-
-			if err := r.DB.Create(&metrics).Error; err != nil {
+			metrics := beehiivSnapshot(post, capturedAt)
+			err := r.DB.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "post_id"}, {Name: "captured_at"}},
+				UpdateAll: true,
+			}).Create(&metrics).Error
+			if err != nil {
 				log.Printf("Error saving metrics for post %s: %v", post.ID, err)
 				continue
 			}
 		}
-		
-		page++
+		if totalPages == 0 && len(posts.Data) < beehiiv.PostsPageSize {
+			break
+		}
 	}
 
+	if len(failedPages) > 0 {
+		return fmt.Errorf("failed to get %d of %d posts pages %v: %w", len(failedPages), totalPages, failedPages, lastErr)
+	}
 	return nil
 }
 
+// beehiivSnapshot turns a post's stats into a snapshot taken at capturedAt
+func beehiivSnapshot(post beehiiv.Post, capturedAt time.Time) models.BeehiivPostMetrics {
+	// Calculate rates
+	emailOpenRate := float64(0)
+	if post.Stats.Email.Delivered > 0 {
+		emailOpenRate = float64(post.Stats.Email.UniqueOpens) / float64(post.Stats.Email.Delivered) * 100
+	}
+
+	emailClickRate := float64(0)
+	if post.Stats.Email.UniqueOpens > 0 {
+		emailClickRate = float64(post.Stats.Email.UniqueClicks) / float64(post.Stats.Email.UniqueOpens) * 100
+	}
+
+	webClickRate := float64(0)
+	if post.Stats.Web.Views > 0 {
+		webClickRate = float64(post.Stats.Web.Clicks) / float64(post.Stats.Web.Views) * 100
+	}
+
+	return models.BeehiivPostMetrics{
+		PostID:            post.ID,
+		Title:             post.Title,
+		Slug:              post.Slug,
+		PublishDate:       time.Unix(post.PublishDate, 0),
+
+		EmailRecipients:   post.Stats.Email.Recipients,
+		EmailDelivered:    post.Stats.Email.Delivered,
+		EmailOpens:        post.Stats.Email.Opens,
+		EmailUniqueOpens:  post.Stats.Email.UniqueOpens,
+		EmailClicks:       post.Stats.Email.Clicks,
+		EmailUniqueClicks: post.Stats.Email.UniqueClicks,
+		EmailOpenRate:     emailOpenRate,
+		EmailClickRate:    emailClickRate,
+
+		WebViews:         post.Stats.Web.Views,
+		WebClicks:        post.Stats.Web.Clicks,
+		WebClickRate:     webClickRate,
+
+		TotalEngagements: post.Stats.Email.UniqueOpens + post.Stats.Email.UniqueClicks + post.Stats.Web.Views + post.Stats.Web.Clicks,
+
+		CapturedAt:       capturedAt,
+	}
+}
+
+// latest reads the most recent snapshot of each post
+func (r *BeehiivMetricsRepository) latest() *gorm.DB {
+	return r.DB.Table(models.LatestBeehiivPostMetricsView)
+}
+
+// GetPostMetrics returns the latest snapshot of every post first stored in the last days
+// days, i.e. whose earliest snapshot was captured since then, newest publish date first
 func (r *BeehiivMetricsRepository) GetPostMetrics(days int) ([]models.BeehiivPostMetrics, error) {
 	var metrics []models.BeehiivPostMetrics
-	err := r.DB.Where("created_at >= ?", time.Now().AddDate(0, 0, -days)).
+	firstStored := r.DB.Model(&models.BeehiivPostMetrics{}).
+		Select("post_id").
+		Group("post_id").
+		Having("MIN(captured_at) >= ?", time.Now().AddDate(0, 0, -days))
+	err := r.latest().Where("post_id IN (?)", firstStored).
 		Order("publish_date desc").
 		Find(&metrics).Error
 	if err != nil {
@@ -130,7 +169,7 @@ func (r *BeehiivMetricsRepository) GetWeekPostMetrics() (BeehiivWeekMetrics, err
 			Date: time.Now(),
 		}
 
-	err := r.DB.Where("publish_date >= ?", time.Now().AddDate(0, 0, -7)).
+	err := r.latest().Where("publish_date >= ?", time.Now().AddDate(0, 0, -7)).
 		Order("publish_date desc").
 		Find(&metrics).Error
 	// get metrics and average them
@@ -173,7 +212,7 @@ func (r *BeehiivMetricsRepository) GetWeekAlphaMetrics() (BeehiivWeekMetrics, er
 			Date: time.Now(),
 		}
 
-	err := r.DB.Where("title LIKE ?", "DeFi Alpha:%").Limit(1).
+	err := r.latest().Where("title LIKE ?", "DeFi Alpha:%").Limit(1).
 		Order("publish_date desc").
 		Find(&metrics).Error
 	// get metrics and average them
@@ -215,10 +254,11 @@ func (r *BeehiivMetricsRepository) GetWeekAlphaMetrics() (BeehiivWeekMetrics, er
 	return metricsAverage, nil
 }
 
+// GetMetricsByPostID returns every snapshot of a post, the most recent first
 func (r *BeehiivMetricsRepository) GetMetricsByPostID(postID string) ([]models.BeehiivPostMetrics, error) {
 	var metrics []models.BeehiivPostMetrics
 	err := r.DB.Where("post_id = ?", postID).
-		Order("created_at desc").
+		Order("captured_at desc").
 		Find(&metrics).Error
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch metrics for post: %w", err)
@@ -229,7 +269,7 @@ func (r *BeehiivMetricsRepository) GetMetricsByPostID(postID string) ([]models.B
 // Get top performing posts by email open rate
 func (r *BeehiivMetricsRepository) GetTopPerformingPosts(limit int) ([]models.BeehiivPostMetrics, error) {
 	var metrics []models.BeehiivPostMetrics
-	err := r.DB.Where("email_recipients > ?", 100). // Minimum sample size
+	err := r.latest().Where("email_recipients > ?", 100). // Minimum sample size
 		Order("email_open_rate desc").
 		Limit(limit).
 		Find(&metrics).Error
@@ -241,7 +281,7 @@ func (r *BeehiivMetricsRepository) GetTopPerformingPosts(limit int) ([]models.Be
 
 func (r *BeehiivMetricsRepository) GetFreePostsMetrics() ([]models.BeehiivPostMetrics, error) {
 	var metrics []models.BeehiivPostMetrics
-	err := r.DB.Where("NOT title LIKE ?", "DeFi Alpha:%").Order("publish_date desc").Limit(6).Find(&metrics).Error
+	err := r.latest().Where("NOT title LIKE ?", "DeFi Alpha:%").Order("publish_date desc").Limit(6).Find(&metrics).Error
 		
 	if err != nil {
 		return metrics, fmt.Errorf("failed to fetch latest post metrics: %w", err)
@@ -253,7 +293,7 @@ func (r *BeehiivMetricsRepository) GetFreePostsMonthlyMetrics() ([]models.Beehii
 	var metrics []models.BeehiivPostMetrics
 	// get last month date
 	lastMonth := time.Now().AddDate(0, -1, 0)
-	err := r.DB.Where("NOT title LIKE ? AND publish_date >= ?", "DeFi Alpha:%", lastMonth).Order("publish_date desc").Find(&metrics).Error
+	err := r.latest().Where("NOT title LIKE ? AND publish_date >= ?", "DeFi Alpha:%", lastMonth).Order("publish_date desc").Find(&metrics).Error
 		
 	if err != nil {
 		return metrics, fmt.Errorf("failed to fetch latest post metrics: %w", err)
@@ -265,7 +305,7 @@ func (r *BeehiivMetricsRepository) GetAlphaPostsMonthlyMetrics() ([]models.Beehi
 	var metrics []models.BeehiivPostMetrics
 	// get last month date
 	lastMonth := time.Now().AddDate(0, -1, 0)
-	err := r.DB.Where("title LIKE ? AND publish_date >= ?", "DeFi Alpha:%", lastMonth).Order("publish_date desc").Find(&metrics).Error
+	err := r.latest().Where("title LIKE ? AND publish_date >= ?", "DeFi Alpha:%", lastMonth).Order("publish_date desc").Find(&metrics).Error
 		
 	if err != nil {
 		return metrics, fmt.Errorf("failed to fetch latest post metrics: %w", err)
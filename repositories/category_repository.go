@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"gorm.io/gorm"
+	"thedefiant.io/analytics/models"
+	"thedefiant.io/analytics/utils"
+)
+
+// NoSubCategory labels the posts of a main category that have no subcategory
+const NoSubCategory = "(none)"
+
+// ErrCategoryNotFound is returned when no post has the requested main category
+var ErrCategoryNotFound = errors.New("category not found")
+
+// CategoryPeriod is a category's numbers over one period
+type CategoryPeriod struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Views int64  `json:"views"`
+	// Posts is the number of posts in the category that had views in the period
+	Posts int64 `json:"posts"`
+	// PublishedPosts is the number of posts published in the category in the period
+	PublishedPosts int64 `json:"publishedPosts"`
+	// AverageViews is Views divided by Posts
+	AverageViews float64 `json:"averageViews"`
+}
+
+// CategoryRollup is a main category, or one of its subcategories, over a period compared
+// with the period of the same length before it. ViewsChangePercent is nil when the
+// previous period had no views.
+type CategoryRollup struct {
+	MainCategory       string         `json:"mainCategory"`
+	SubCategory        *string        `json:"subCategory,omitempty"`
+	Current            CategoryPeriod `json:"current"`
+	Previous           CategoryPeriod `json:"previous"`
+	ViewsChange        int64          `json:"viewsChange"`
+	ViewsChangePercent *float64       `json:"viewsChangePercent"`
+}
+
+// categoryRow is one row of the category aggregation queries. Each count is for the
+// current period, and the one prefixed with Previous for the period before it.
+type categoryRow struct {
+	MainCategory      string
+	SubCategory       *string
+	Views             int64
+	PreviousViews     int64
+	Posts             int64
+	PreviousPosts     int64
+	Published         int64
+	PreviousPublished int64
+}
+
+// GetCategoryRollups aggregates stored daily views and publish counts per main category,
+// or per main category and subcategory when bySubCategory is set, for from..to and the
+// period before it. Every category with posts is listed, with zeros when it was quiet, and
+// posts without a subcategory are grouped under NoSubCategory. mainCategory limits the
+// result to one main category when not empty; ErrCategoryNotFound is returned when it has
+// no posts at all. Rollups are ordered by views, largest first.
+func (r *PostRepository) GetCategoryRollups(from, to, mainCategory string, bySubCategory bool) ([]CategoryRollup, error) {
+	prevFrom, prevTo, err := utils.GetPreviousPeriod(from, to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date range %s to %s: %w", from, to, err)
+	}
+
+	group, groupSelect := "posts.main_category", "posts.main_category, NULL AS sub_category"
+	if bySubCategory {
+		group = "posts.main_category, posts.sub_category"
+		groupSelect = "posts.main_category, COALESCE(posts.sub_category, '" + NoSubCategory + "') AS sub_category"
+	}
+	categories := func() *gorm.DB {
+		query := r.DB.Model(&models.Post{}).Where("posts.main_category IS NOT NULL")
+		if mainCategory != "" {
+			query = query.Where("posts.main_category = ?", mainCategory)
+		}
+		return query.Group(group)
+	}
+
+	var groupRows, viewRows, publishedRows []categoryRow
+	if err := categories().Select(groupSelect).Order(group).Scan(&groupRows).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch categories: %w", err)
+	}
+	if mainCategory != "" && len(groupRows) == 0 {
+		return nil, ErrCategoryNotFound
+	}
+	err = categories().
+		Select(groupSelect+`,
+			COALESCE(SUM(d.views) FILTER (WHERE d.date >= ?), 0) AS views,
+			COALESCE(SUM(d.views) FILTER (WHERE d.date <= ?), 0) AS previous_views,
+			COUNT(DISTINCT d.post_id) FILTER (WHERE d.date >= ?) AS posts,
+			COUNT(DISTINCT d.post_id) FILTER (WHERE d.date <= ?) AS previous_posts`, from, prevTo, from, prevTo).
+		Joins("JOIN post_daily_views d ON d.post_id = posts.id AND d.date BETWEEN ? AND ?", prevFrom, to).
+		Scan(&viewRows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate category views: %w", err)
+	}
+	err = categories().
+		Select(groupSelect+`,
+			COUNT(*) FILTER (WHERE DATE(posts.published_at) >= ?) AS published,
+			COUNT(*) FILTER (WHERE DATE(posts.published_at) <= ?) AS previous_published`, from, prevTo).
+		Where("DATE(posts.published_at) BETWEEN ? AND ?", prevFrom, to).
+		Scan(&publishedRows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count published category posts: %w", err)
+	}
+
+	rollups := make(map[string]*CategoryRollup)
+	var ordered []*CategoryRollup
+	rollupFor := func(row categoryRow) *CategoryRollup {
+		key := row.MainCategory
+		if row.SubCategory != nil {
+			key += "/" + *row.SubCategory
+		}
+		if rollup, ok := rollups[key]; ok {
+			return rollup
+		}
+		rollup := &CategoryRollup{
+			MainCategory: row.MainCategory,
+			SubCategory:  row.SubCategory,
+			Current:      CategoryPeriod{From: from, To: to},
+			Previous:     CategoryPeriod{From: prevFrom, To: prevTo},
+		}
+		rollups[key] = rollup
+		ordered = append(ordered, rollup)
+		return rollup
+	}
+	for _, row := range groupRows {
+		rollupFor(row)
+	}
+	for _, row := range viewRows {
+		rollup := rollupFor(row)
+		rollup.Current.Views, rollup.Previous.Views = row.Views, row.PreviousViews
+		rollup.Current.Posts, rollup.Previous.Posts = row.Posts, row.PreviousPosts
+	}
+	for _, row := range publishedRows {
+		rollup := rollupFor(row)
+		rollup.Current.PublishedPosts, rollup.Previous.PublishedPosts = row.Published, row.PreviousPublished
+	}
+
+	result := make([]CategoryRollup, 0, len(ordered))
+	for _, rollup := range ordered {
+		for _, period := range []*CategoryPeriod{&rollup.Current, &rollup.Previous} {
+			if period.Posts > 0 {
+				period.AverageViews = float64(period.Views) / float64(period.Posts)
+			}
+		}
+		rollup.ViewsChange = rollup.Current.Views - rollup.Previous.Views
+		if rollup.Previous.Views > 0 {
+			percent := float64(rollup.ViewsChange) / float64(rollup.Previous.Views) * 100
+			rollup.ViewsChangePercent = &percent
+		}
+		result = append(result, *rollup)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Current.Views > result[j].Current.Views
+	})
+	return result, nil
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"thedefiant.io/analytics/models"
+)
+
+// Author credit modes, chosen with the AUTHOR_CREDIT environment variable
+const (
+	// AuthorCreditFull gives every author of a post all of its views (the default)
+	AuthorCreditFull = "full"
+	// AuthorCreditSplit divides a post's views evenly between its authors
+	AuthorCreditSplit = "split"
+)
+
+// AuthorCreditMode returns how co-written posts are credited to their authors
+func AuthorCreditMode() string {
+	if os.Getenv("AUTHOR_CREDIT") == AuthorCreditSplit {
+		return AuthorCreditSplit
+	}
+	return AuthorCreditFull
+}
+
+// postAuthorCredits returns a subquery of (post_id, author_id, credit) rows, where credit
+// is the share of a post's numbers attributed to the author under AuthorCreditMode
+func postAuthorCredits(db *gorm.DB) *gorm.DB {
+	credit := "1.0"
+	if AuthorCreditMode() == AuthorCreditSplit {
+		credit = "1.0 / COUNT(*) OVER (PARTITION BY post_id)"
+	}
+	return db.Table("post_authors").Select("post_id, author_id, " + credit + " AS credit")
+}
+
+// postAuthorIDs returns the distinct authors of a post coming from Sanity, primary first.
+// Posts read without their author list fall back to the primary author alone.
+func postAuthorIDs(post models.Post) []string {
+	ids := post.AuthorIds
+	if len(ids) == 0 && post.AuthorId != nil {
+		ids = []string{*post.AuthorId}
+	}
+	seen := make(map[string]bool, len(ids))
+	unique := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, id)
+	}
+	return unique
+}
+
+// hasCoAuthorIDs reports whether any entry of the Sanity authors array resolved to an id
+func hasCoAuthorIDs(post models.Post) bool {
+	for i, id := range post.AuthorIds {
+		if i > 0 && id != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// syncPostAuthors makes post_authors list exactly the authors of the post
+func syncPostAuthors(tx *gorm.DB, post models.Post) error {
+	if post.AuthorRefCount > 0 && !hasCoAuthorIDs(post) {
+		log.Printf("Post %s has %d entries in its Sanity authors array but none resolved to an author id", *post.ID, post.AuthorRefCount)
+	}
+	ids := postAuthorIDs(post)
+	stale := tx.Where("post_id = ?", *post.ID)
+	if len(ids) > 0 {
+		stale = stale.Where("author_id NOT IN ?", ids)
+	}
+	if err := stale.Delete(&models.PostAuthor{}).Error; err != nil {
+		return fmt.Errorf("failed to remove old authors of post %s: %w", *post.ID, err)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	rows := make([]models.PostAuthor, len(ids))
+	for i := range ids {
+		rows[i] = models.PostAuthor{PostID: post.ID, AuthorID: &ids[i], Position: i}
+	}
+	err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "post_id"}, {Name: "author_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"position"}),
+	}).Create(&rows).Error
+	if err != nil {
+		return fmt.Errorf("failed to store authors of post %s: %w", *post.ID, err)
+	}
+	return nil
+}
+
+// SeedPostAuthors credits the primary author of every post that has no post_authors rows
+// yet, i.e. posts stored before co-authors were tracked. It is safe to run repeatedly.
+func (r *PostRepository) SeedPostAuthors() error {
+	err := r.DB.Exec(`INSERT INTO post_authors (post_id, author_id, position)
+		SELECT p.id, p.author_id, 0
+		FROM posts p
+		WHERE p.author_id IS NOT NULL
+		AND NOT EXISTS (SELECT 1 FROM post_authors pa WHERE pa.post_id = p.id)`).Error
+	if err != nil {
+		return fmt.Errorf("failed to seed post authors: %w", err)
+	}
+	return nil
+}
+
+// getAllPostAuthorIDs returns the stored authors of every post, primary first
+func getAllPostAuthorIDs(db *gorm.DB) (map[string][]string, error) {
+	var rows []models.PostAuthor
+	if err := db.Order("post_id, position").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch post authors: %w", err)
+	}
+	authors := make(map[string][]string)
+	for _, row := range rows {
+		authors[*row.PostID] = append(authors[*row.PostID], *row.AuthorID)
+	}
+	return authors, nil
+}
@@ -0,0 +1,443 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"thedefiant.io/analytics/models"
+	"thedefiant.io/analytics/services/analytics"
+	"thedefiant.io/analytics/utils"
+)
+
+// longestWindowDays is the length of the longest rolling window; posts published longer
+// ago than that no longer change any window column
+func longestWindowDays() int {
+	rangeTypes := utils.GetRangeTypes()
+	return utils.GetDaysFromRangeType(rangeTypes[len(rangeTypes)-1])
+}
+
+// windowPosts returns the posts with a slug that are inside the longest rolling window on
+// the given date
+func (r *PostRepository) windowPosts(date string) ([]models.Post, error) {
+	var posts []models.Post
+	err := r.DB.Where("DATE(published_at) <= ?", date).
+		Where("DATE(published_at) > ?::date - ?::int", date, longestWindowDays()).
+		Where("slug is not NULL").
+		Find(&posts).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch posts for %s: %w", date, err)
+	}
+	return posts, nil
+}
+
+// UpdateDailyViews fetches the views the posts still inside the longest rolling window
+// received on the given date and stores them in post_daily_views. Posts without traffic
+// get an explicit zero row. Older posts are only ingested by backfills. It waits for any
+// backfill or catch-up holding the ingestion lock to finish first.
+func (r *PostRepository) UpdateDailyViews(date string) ([]models.PostDailyView, error) {
+	unlock, err := r.lockIngestion(true)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	posts, err := r.windowPosts(date)
+	if err != nil {
+		return nil, err
+	}
+	if len(posts) == 0 {
+		log.Printf("No posts found for %s", date)
+		return nil, nil
+	}
+
+	return r.storeDailyViews(date, date, posts)
+}
+
+// dailyViewRows builds one post_daily_views row per post and day from start to end, from
+// the publication day on, summing the metrics of every path of the post. Days without
+// metrics get zero rows.
+func dailyViewRows(start, end time.Time, posts []models.Post, paths *postPaths, dailyMetrics map[string]map[string]analytics.PageMetrics, partial bool) []models.PostDailyView {
+	var rows []models.PostDailyView
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		date := utils.FormatDate(day)
+		for _, post := range posts {
+			if utils.FormatDate(post.PublishedAt) > date {
+				continue
+			}
+			var metrics analytics.PageMetrics
+			for _, path := range paths.byPost[*post.ID] {
+				metrics.Add(dailyMetrics[date][path])
+			}
+			rows = append(rows, models.PostDailyView{
+				PostID:             post.ID,
+				Date:               day,
+				Views:              metrics.Views,
+				Users:              metrics.Users,
+				Sessions:           metrics.Sessions,
+				EngagedSessions:    metrics.EngagedSessions,
+				EngagementDuration: metrics.EngagementDuration,
+				SessionDuration:    metrics.SessionDuration,
+				Partial:            partial,
+			})
+		}
+	}
+	return rows
+}
+
+// storeDailyViews fetches per-day views for the posts between startDate and endDate
+// and upserts one row per post and day
+func (r *PostRepository) storeDailyViews(startDate, endDate string, posts []models.Post) ([]models.PostDailyView, error) {
+	start, err := utils.ParseDate(startDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start date %q: %w", startDate, err)
+	}
+	end, err := utils.ParseDate(endDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end date %q: %w", endDate, err)
+	}
+
+	paths, err := loadPostPaths(r.DB, r.Analytics.NormalizePath, posts)
+	if err != nil {
+		return nil, err
+	}
+
+	dailyMetrics, metadata, err := r.Analytics.GetDailyPageMetrics(startDate, endDate, paths.paths)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily page metrics: %w", err)
+	}
+	if metadata.Partial() {
+		log.Printf("Partial GA data from %s to %s: %+v", startDate, endDate, metadata)
+	}
+
+	rows := dailyViewRows(start, end, posts, paths, dailyMetrics, metadata.Partial())
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	err = r.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "post_id"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"views", "users", "sessions", "engaged_sessions", "engagement_duration", "session_duration", "partial", "updated_at",
+		}),
+	}).CreateInBatches(&rows, 500).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to store daily views: %w", err)
+	}
+	return rows, nil
+}
+
+// RefreshRollingViews recomputes the window columns of posts whose windows are still open
+// or closed yesterday from post_daily_views. Closed windows keep the GA total set by
+// UpdateClosedWindowViews.
+func (r *PostRepository) RefreshRollingViews() error {
+	for _, rangeType := range utils.GetRangeTypes() {
+		since := utils.GetDateNDaysAgo(utils.GetDaysFromRangeType(rangeType))
+		if err := r.refreshWindowViews(rangeType, since); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refreshWindowViews sets a window column to the views a post received during its first
+// N days after publication. Only posts published on or after publishedSince are touched
+// (all posts when empty), and only if their publication day has been ingested, so values
+// imported before post_daily_views existed are not overwritten with partial sums. Windows
+// already holding GA's total for the closed window are left alone.
+func (r *PostRepository) refreshWindowViews(rangeType string, publishedSince string) error {
+	fieldName := utils.GetDBFieldName(rangeType)
+	if fieldName == "" {
+		return fmt.Errorf("invalid range type: %s", rangeType)
+	}
+	days := utils.GetDaysFromRangeType(rangeType)
+
+	query := `UPDATE posts SET ` + fieldName + ` = COALESCE((
+			SELECT SUM(d.views) FROM post_daily_views d
+			WHERE d.post_id = posts.id
+			AND d.date >= DATE(posts.published_at)
+			AND d.date < DATE(posts.published_at) + ?::int
+		), 0)
+		WHERE EXISTS (
+			SELECT 1 FROM post_daily_views d
+			WHERE d.post_id = posts.id AND d.date = DATE(posts.published_at)
+		)
+		AND NOT EXISTS (
+			SELECT 1 FROM post_window_syncs s
+			WHERE s.post_id = posts.id AND s.range_type = ? AND s.source = ?
+		)`
+	args := []interface{}{days, rangeType, models.WindowSourceGA}
+	if publishedSince != "" {
+		query += ` AND DATE(published_at) >= ?`
+		args = append(args, publishedSince)
+	}
+
+	if err := r.DB.Exec(query, args...).Error; err != nil {
+		return fmt.Errorf("failed to refresh %s views: %w", rangeType, err)
+	}
+
+	// Move the window's high-water mark to the last day covered by daily data
+	syncQuery := `INSERT INTO post_window_syncs (post_id, range_type, synced_through, source, updated_at)
+		SELECT p.id, ?, LEAST(DATE(p.published_at) + ?::int - 1, MAX(d.date)), ?, NOW()
+		FROM posts p
+		JOIN post_daily_views d ON d.post_id = p.id
+			AND d.date >= DATE(p.published_at)
+			AND d.date < DATE(p.published_at) + ?::int
+		WHERE EXISTS (
+			SELECT 1 FROM post_daily_views f
+			WHERE f.post_id = p.id AND f.date = DATE(p.published_at)
+		)`
+	syncArgs := []interface{}{rangeType, days, models.WindowSourceDaily, days}
+	if publishedSince != "" {
+		syncQuery += ` AND DATE(p.published_at) >= ?`
+		syncArgs = append(syncArgs, publishedSince)
+	}
+	syncQuery += ` GROUP BY p.id, p.published_at
+		ON CONFLICT (post_id, range_type) DO UPDATE
+		SET synced_through = EXCLUDED.synced_through, updated_at = EXCLUDED.updated_at
+		WHERE post_window_syncs.source <> ?`
+	syncArgs = append(syncArgs, models.WindowSourceGA)
+
+	if err := r.DB.Exec(syncQuery, syncArgs...).Error; err != nil {
+		return fmt.Errorf("failed to update %s high-water marks: %w", rangeType, err)
+	}
+	return nil
+}
+
+// CatchUpViews fills the daily views that are missing for posts whose windows are not yet
+// complete according to their high-water marks, e.g. after the nightly job was skipped
+// because the service was down, and then re-derives the affected windows. Only posts
+// inside the longest rolling window are looked at, and days before post_daily_views
+// started being filled are left to an explicit backfill. It returns ErrBackfillRunning
+// when a backfill or another catch-up holds the ingestion lock.
+func (r *PostRepository) CatchUpViews() error {
+	unlock, err := r.lockIngestion(false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	var trackingStart sql.NullTime
+	if err := r.DB.Model(&models.PostDailyView{}).Select("MIN(date)").Row().Scan(&trackingStart); err != nil {
+		return fmt.Errorf("failed to find first ingested day: %w", err)
+	}
+	if !trackingStart.Valid {
+		log.Println("No daily views stored yet, nothing to catch up")
+		return nil
+	}
+	yesterday := utils.GetYesterdayDate()
+
+	windows := make([]string, 0, len(utils.GetRangeTypes()))
+	for _, rangeType := range utils.GetRangeTypes() {
+		windows = append(windows, fmt.Sprintf("('%s', %d)", rangeType, utils.GetDaysFromRangeType(rangeType)))
+	}
+
+	var missingDays []time.Time
+	err = r.DB.Raw(`WITH windows(range_type, days) AS (VALUES `+strings.Join(windows, ", ")+`)
+		SELECT DISTINCT gs.day::date AS day
+		FROM posts p
+		CROSS JOIN windows w
+		LEFT JOIN post_window_syncs s ON s.post_id = p.id AND s.range_type = w.range_type
+		CROSS JOIN LATERAL generate_series(
+			GREATEST(DATE(p.published_at), ?::date),
+			LEAST(DATE(p.published_at) + w.days - 1, ?::date),
+			interval '1 day'
+		) AS gs(day)
+		LEFT JOIN post_daily_views d ON d.post_id = p.id AND d.date = gs.day::date
+		WHERE p.slug IS NOT NULL
+		AND p.deleted_at IS NULL
+		AND DATE(p.published_at) > ?::date - ?::int
+		AND (s.synced_through IS NULL OR s.synced_through < DATE(p.published_at) + w.days - 1)
+		AND d.id IS NULL
+		ORDER BY day`, utils.FormatDate(trackingStart.Time), yesterday, yesterday, longestWindowDays()).Scan(&missingDays).Error
+	if err != nil {
+		return fmt.Errorf("failed to find missing daily views: %w", err)
+	}
+	if len(missingDays) == 0 {
+		log.Println("Daily views are up to date, nothing to catch up")
+		return nil
+	}
+	log.Printf("Catching up %d missing days of views starting %s", len(missingDays), utils.FormatDate(missingDays[0]))
+
+	for _, day := range missingDays {
+		date := utils.FormatDate(day)
+		posts, err := r.windowPosts(date)
+		if err != nil {
+			return err
+		}
+		if len(posts) == 0 {
+			continue
+		}
+		if err := r.backfillChunk(date, date, posts); err != nil {
+			return err
+		}
+	}
+
+	// Any window that overlaps a filled day has to be re-derived
+	for _, rangeType := range utils.GetRangeTypes() {
+		since := utils.FormatDate(missingDays[0].AddDate(0, 0, -utils.GetDaysFromRangeType(rangeType)))
+		if err := r.refreshWindowViews(rangeType, since); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetPostDailyViews returns the stored daily views of a post between two dates (inclusive)
+func (r *PostRepository) GetPostDailyViews(postID, from, to string) ([]models.PostDailyView, error) {
+	var views []models.PostDailyView
+	err := r.DB.Where("post_id = ? AND date BETWEEN ? AND ?", postID, from, to).
+		Order("date asc").
+		Find(&views).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch daily views for post %s: %w", postID, err)
+	}
+	return views, nil
+}
+
+// postMetricsQuery aggregates post_daily_views into one row of PostMetrics per post,
+// limited to each post's first days after publication when days is positive
+func (r *PostRepository) postMetricsQuery(days int) *gorm.DB {
+	query := r.DB.Table("post_daily_views d").
+		Select(`d.post_id,
+			SUM(d.users) AS users,
+			SUM(d.sessions) AS sessions,
+			SUM(d.engagement_duration) AS engagement_duration,
+			COALESCE(SUM(d.session_duration) / NULLIF(SUM(d.sessions), 0), 0) AS average_session_duration,
+			COALESCE(SUM(d.engaged_sessions)::float / NULLIF(SUM(d.sessions), 0), 0) AS engagement_rate,
+			COALESCE(1 - SUM(d.engaged_sessions)::float / NULLIF(SUM(d.sessions), 0), 0) AS bounce_rate`).
+		Joins("JOIN posts p ON p.id = d.post_id").
+		Group("d.post_id")
+	if days > 0 {
+		query = query.Where("d.date < DATE(p.published_at) + ?::int", days)
+	}
+	return query
+}
+
+// PostViewTotals is a post's total views over an explicit date range
+type PostViewTotals struct {
+	ID           *string   `json:"id"`
+	Title        *string   `json:"title"`
+	Slug         *string   `json:"slug"`
+	AuthorId     *string   `json:"authorId"`
+	MainCategory *string   `json:"mainCategory"`
+	SubCategory  *string   `json:"subCategory"`
+	PublishedAt  time.Time `json:"publishedAt"`
+	Views        int64     `json:"views"`
+}
+
+const (
+	ViewsSourceStored = "stored"
+	ViewsSourceLive   = "live"
+)
+
+// GetViewsBetween returns every post's views between two dates (inclusive), most viewed
+// first. The totals come from post_daily_views when it covers the whole range, otherwise
+// (or when live is set) from a live GA query. The second return value is the source used.
+func (r *PostRepository) GetViewsBetween(from, to string, live bool) ([]PostViewTotals, string, error) {
+	if !live {
+		covered, err := dailyViewsCover(r.DB, from, to)
+		if err != nil {
+			return nil, "", err
+		}
+		live = !covered
+	}
+
+	if !live {
+		var totals []PostViewTotals
+		err := r.DB.Model(&models.Post{}).
+			Select("posts.id, posts.title, posts.slug, posts.author_id, posts.main_category, posts.sub_category, posts.published_at, COALESCE(SUM(d.views), 0) AS views").
+			Joins("LEFT JOIN post_daily_views d ON d.post_id = posts.id AND d.date BETWEEN ? AND ?", from, to).
+			Where("DATE(posts.published_at) <= ?", to).
+			Where("posts.slug is not NULL").
+			Group("posts.id").
+			Order("views desc").
+			Scan(&totals).Error
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to sum stored views: %w", err)
+		}
+		return totals, ViewsSourceStored, nil
+	}
+
+	var posts []models.Post
+	err := r.DB.Where("DATE(published_at) <= ?", to).Where("slug is not NULL").Find(&posts).Error
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch posts for %s: %w", to, err)
+	}
+	if len(posts) == 0 {
+		return []PostViewTotals{}, ViewsSourceLive, nil
+	}
+
+	paths, err := loadPostPaths(r.DB, r.Analytics.NormalizePath, posts)
+	if err != nil {
+		return nil, "", err
+	}
+	pageViews, metadata, err := r.Analytics.GetPageViewsBetween(from, to, paths.paths)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get page views: %w", err)
+	}
+	if metadata.Partial() {
+		log.Printf("Partial GA data from %s to %s: %+v", from, to, metadata)
+	}
+
+	return liveViewTotals(posts, paths, pageViews), ViewsSourceLive, nil
+}
+
+// liveViewTotals sums the GA views of every path of each post, most viewed first
+func liveViewTotals(posts []models.Post, paths *postPaths, pageViews map[string]int64) []PostViewTotals {
+	totals := make([]PostViewTotals, len(posts))
+	for i, post := range posts {
+		totals[i] = PostViewTotals{
+			ID:           post.ID,
+			Title:        post.Title,
+			Slug:         post.Slug,
+			AuthorId:     post.AuthorId,
+			MainCategory: post.MainCategory,
+			SubCategory:  post.SubCategory,
+			PublishedAt:  post.PublishedAt,
+			Views:        paths.sum(*post.ID, pageViews),
+		}
+	}
+	sort.SliceStable(totals, func(i, j int) bool {
+		return totals[i].Views > totals[j].Views
+	})
+	return totals
+}
+
+// dailyViewsCover reports whether post_daily_views has been filled for every day in the
+// range, i.e. whether each day between from and to has stored views. Any day the site
+// got views has at least one row, so a missing day means it was never ingested.
+func dailyViewsCover(db *gorm.DB, from, to string) (bool, error) {
+	days, err := rangeDays(from, to)
+	if err != nil {
+		return false, err
+	}
+
+	var stored int64
+	err = db.Model(&models.PostDailyView{}).
+		Where("date BETWEEN ? AND ?", from, to).
+		Distinct("date").
+		Count(&stored).Error
+	if err != nil {
+		return false, fmt.Errorf("failed to count stored days: %w", err)
+	}
+	return stored == days, nil
+}
+
+// rangeDays returns the number of days from one date to another, both included
+func rangeDays(from, to string) (int64, error) {
+	start, err := utils.ParseDate(from)
+	if err != nil {
+		return 0, fmt.Errorf("invalid start date %s: %w", from, err)
+	}
+	end, err := utils.ParseDate(to)
+	if err != nil {
+		return 0, fmt.Errorf("invalid end date %s: %w", to, err)
+	}
+	return int64(end.Sub(start).Hours()/24) + 1, nil
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"thedefiant.io/analytics/models"
+	"thedefiant.io/analytics/services/analytics"
+)
+
+func date(s string) time.Time {
+	day, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return day
+}
+
+func testPosts() ([]models.Post, *postPaths) {
+	postA, postB := "post-a", "post-b"
+	posts := []models.Post{
+		{ID: &postA, PublishedAt: date("2024-05-01").Add(15 * time.Hour)},
+		{ID: &postB, PublishedAt: date("2024-05-02").Add(9 * time.Hour)},
+	}
+	paths := newPostPaths(func(path string) string { return path })
+	paths.add(&postA, "/defi/lending/a")
+	paths.add(&postA, "/defi/lending/a-old")
+	paths.add(&postB, "/nfts/art/b")
+	return posts, paths
+}
+
+func TestDailyViewRows(t *testing.T) {
+	posts, paths := testPosts()
+	metrics := map[string]map[string]analytics.PageMetrics{
+		"2024-05-01": {
+			"/defi/lending/a": {Views: 10, Sessions: 4},
+			// Views of a post before its publication day can't be credited to it
+			"/nfts/art/b": {Views: 99},
+		},
+		"2024-05-02": {
+			"/defi/lending/a":     {Views: 5, Sessions: 2},
+			"/defi/lending/a-old": {Views: 2, Sessions: 1},
+			"/nfts/art/b":         {Views: 7, Sessions: 3},
+		},
+	}
+
+	rows := dailyViewRows(date("2024-05-01"), date("2024-05-03"), posts, paths, metrics, true)
+
+	want := []struct {
+		post     string
+		date     string
+		views    int64
+		sessions int64
+	}{
+		{"post-a", "2024-05-01", 10, 4},
+		{"post-a", "2024-05-02", 7, 3},
+		{"post-b", "2024-05-02", 7, 3},
+		// Days without traffic get explicit zero rows
+		{"post-a", "2024-05-03", 0, 0},
+		{"post-b", "2024-05-03", 0, 0},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(want))
+	}
+	for i, w := range want {
+		row := rows[i]
+		if *row.PostID != w.post || row.Date.Format("2006-01-02") != w.date || row.Views != w.views || row.Sessions != w.sessions {
+			t.Errorf("row %d = %s %s %d views %d sessions, want %s %s %d views %d sessions",
+				i, *row.PostID, row.Date.Format("2006-01-02"), row.Views, row.Sessions, w.post, w.date, w.views, w.sessions)
+		}
+		if !row.Partial {
+			t.Errorf("row %d is not marked partial", i)
+		}
+	}
+}
+
+func TestRangeDays(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     int64
+		wantErr  bool
+	}{
+		{"2024-05-01", "2024-05-01", 1, false},
+		{"2024-05-01", "2024-05-31", 31, false},
+		{"2024-02-01", "2024-02-29", 29, false},
+		{"2023-12-25", "2024-01-05", 12, false},
+		{"2024-05-01", "not-a-date", 0, true},
+		{"2024/05/01", "2024-05-02", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := rangeDays(tt.from, tt.to)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("rangeDays(%s, %s) error = %v, want error %v", tt.from, tt.to, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("rangeDays(%s, %s) = %d, want %d", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestLiveViewTotals(t *testing.T) {
+	posts, paths := testPosts()
+	tests := []struct {
+		name      string
+		pageViews map[string]int64
+		want      []string
+		wantViews []int64
+	}{
+		{
+			name:      "old paths are added to the post",
+			pageViews: map[string]int64{"/defi/lending/a": 3, "/defi/lending/a-old": 6, "/nfts/art/b": 8},
+			want:      []string{"post-a", "post-b"},
+			wantViews: []int64{9, 8},
+		},
+		{
+			name:      "most viewed first",
+			pageViews: map[string]int64{"/defi/lending/a": 1, "/nfts/art/b": 8},
+			want:      []string{"post-b", "post-a"},
+			wantViews: []int64{8, 1},
+		},
+		{
+			name:      "posts without views are kept",
+			pageViews: map[string]int64{},
+			want:      []string{"post-a", "post-b"},
+			wantViews: []int64{0, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			totals := liveViewTotals(posts, paths, tt.pageViews)
+			if len(totals) != len(tt.want) {
+				t.Fatalf("got %d totals, want %d", len(totals), len(tt.want))
+			}
+			for i := range tt.want {
+				if *totals[i].ID != tt.want[i] || totals[i].Views != tt.wantViews[i] {
+					t.Errorf("totals[%d] = %s with %d views, want %s with %d", i, *totals[i].ID, totals[i].Views, tt.want[i], tt.wantViews[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"thedefiant.io/analytics/models"
+	"thedefiant.io/analytics/services/analytics"
+)
+
+const (
+	// postIDChunkSize is how many post IDs go into one IN list
+	postIDChunkSize = 1000
+	// unmatchedPathsMaxRows caps the pagePath rows read from GA, most viewed first
+	unmatchedPathsMaxRows = 10000
+	// unmatchedPathsCacheTTL is how long unmatched paths are served before GA is asked again
+	unmatchedPathsCacheTTL = time.Hour
+)
+
+type unmatchedPathsEntry struct {
+	paths     []UnmatchedPath
+	fetchedAt time.Time
+}
+
+// UnmatchedPath is a GA pagePath with traffic that belongs to no known post
+type UnmatchedPath struct {
+	Path  string `json:"path"`
+	Views int64  `json:"views"`
+}
+
+// GetUnmatchedPaths returns the GA paths with traffic between two dates that match neither
+// the current nor any previous path of a post, most viewed first. Only paths shaped like
+// a post URL (/main/sub/slug) are reported so section and landing pages don't drown out
+// broken mappings. Only the unmatchedPathsMaxRows most viewed GA paths are checked, and
+// results are cached per date range for unmatchedPathsCacheTTL.
+func (r *PostRepository) GetUnmatchedPaths(from, to string, limit int) ([]UnmatchedPath, error) {
+	r.unmatchedMu.Lock()
+	defer r.unmatchedMu.Unlock()
+
+	key := from + "|" + to
+	entry, ok := r.unmatchedCache[key]
+	if !ok || time.Since(entry.fetchedAt) >= unmatchedPathsCacheTTL {
+		paths, err := r.findUnmatchedPaths(from, to)
+		if err != nil {
+			return nil, err
+		}
+		if r.unmatchedCache == nil {
+			r.unmatchedCache = make(map[string]unmatchedPathsEntry)
+		}
+		for cached, old := range r.unmatchedCache {
+			if time.Since(old.fetchedAt) >= unmatchedPathsCacheTTL {
+				delete(r.unmatchedCache, cached)
+			}
+		}
+		entry = unmatchedPathsEntry{paths: paths, fetchedAt: time.Now()}
+		r.unmatchedCache[key] = entry
+	}
+
+	unmatched := entry.paths
+	if limit > 0 && len(unmatched) > limit {
+		unmatched = unmatched[:limit]
+	}
+	return unmatched, nil
+}
+
+func (r *PostRepository) findUnmatchedPaths(from, to string) ([]UnmatchedPath, error) {
+	var posts []models.Post
+	if err := r.DB.Where("slug is not NULL").Find(&posts).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch posts: %w", err)
+	}
+	paths, err := loadPostPaths(r.DB, r.Analytics.NormalizePath, posts)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, metadata, err := r.Analytics.RunReport(analytics.ReportQuery{
+		StartDate:  from,
+		EndDate:    to,
+		Dimensions: []string{"pagePath"},
+		Metrics:    []string{"screenPageViews"},
+		OrderBy:    "screenPageViews",
+		Limit:      unmatchedPathsMaxRows,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get page paths: %w", err)
+	}
+	if metadata.Partial() {
+		log.Printf("Partial GA data for page paths from %s to %s: %+v", from, to, metadata)
+	}
+
+	// GA paths come back normalized, so compare them with the normalized post paths
+	known := make(map[string]bool, len(paths.paths))
+	for _, path := range paths.paths {
+		known[r.Analytics.NormalizePath(path)] = true
+	}
+
+	views := make(map[string]int64)
+	for _, row := range rows {
+		path := row.Dimensions["pagePath"]
+		if known[path] || !isPostShapedPath(path) {
+			continue
+		}
+		views[path] += row.Int("screenPageViews")
+	}
+
+	unmatched := make([]UnmatchedPath, 0, len(views))
+	for path, count := range views {
+		unmatched = append(unmatched, UnmatchedPath{Path: path, Views: count})
+	}
+
+	sort.Slice(unmatched, func(i, j int) bool {
+		return unmatched[i].Views > unmatched[j].Views
+	})
+	return unmatched, nil
+}
+
+// isPostShapedPath reports whether a path has the three segments of a post URL
+func isPostShapedPath(path string) bool {
+	path = strings.SplitN(path, "?", 2)[0]
+	segments := strings.FieldsFunc(path, func(ch rune) bool { return ch == '/' })
+	return len(segments) == 3
+}
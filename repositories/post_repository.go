@@ -1,10 +1,16 @@
 package repository
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"sync"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"thedefiant.io/analytics/models"
 	"thedefiant.io/analytics/services/analytics"
 	"thedefiant.io/analytics/services/sanity"
@@ -15,6 +21,9 @@ type PostRepository struct {
 	DB        *gorm.DB 
 	Sanity    *sanity.Client
 	Analytics *analytics.Client
+
+	unmatchedMu    sync.Mutex
+	unmatchedCache map[string]unmatchedPathsEntry
 }
 
 func NewPostRepository(db *gorm.DB, sanityClient *sanity.Client, analyticsClient *analytics.Client) *PostRepository {
@@ -25,11 +34,21 @@ func NewPostRepository(db *gorm.DB, sanityClient *sanity.Client, analyticsClient
 	}
 }
 
+// sanityPostFilter matches the Sanity documents tracked as posts
+const sanityPostFilter = `_type in ['blog', 'sponsor'] && defined(mainCategory) && defined(subCategory) && !(_id in path('drafts.**'))`
+
+// sanityPostProjection maps a Sanity post document onto models.Post. Co-authors are read
+// from the authors reference array next to the primary author, and tags from both the
+// tags and topics reference arrays. These field names follow the blog and sponsor schemas;
+// authorRefCount and tagRefCount let syncPostAuthors and syncPostTags report posts whose
+// reference arrays no longer match them.
+const sanityPostProjection = `{'id':_id, 'type': _type, title,'slug': slug.current,'authorId': author._ref, 'authorIds': [author._ref] + coalesce(authors[]._ref, []), 'authorRefCount': count(coalesce(authors, [])), 'mainCategory': mainCategory->slug.current, 'subCategory': subCategory->slug.current, 'tags': coalesce(tags[]->{'slug': slug.current, 'name': title}, []) + coalesce(topics[]->{'slug': slug.current, 'name': title}, []), 'tagRefCount': count(coalesce(tags, [])) + count(coalesce(topics, [])),publishedAt, 'createdAt': _createdAt}`
+
 func (r *PostRepository) CreatePost() ([]models.Post, error) {
 	var posts []models.Post
 	twoDaysAgo := utils.GetDateNDaysAgo(2)
 
-	query := `*[_type in ['blog', 'sponsor'] && defined(mainCategory) && defined(subCategory) && !(_id in path('drafts.**')) && publishedAt > $date ]| order(dateTime(publishedAt) desc){'id':_id, title,'slug': slug.current,'authorId': author._ref, 'mainCategory': mainCategory->slug.current, 'subCategory': subCategory->slug.current,publishedAt, 'createdAt': _createdAt}`
+	query := `*[` + sanityPostFilter + ` && publishedAt > $date ]| order(dateTime(publishedAt) desc)` + sanityPostProjection
 	params := map[string]interface{}{
 		"date": twoDaysAgo,
 	}
@@ -44,65 +63,473 @@ func (r *PostRepository) CreatePost() ([]models.Post, error) {
 	}
 
 	for _, post := range posts {
-		err := r.DB.Create(&post).Error
+		if err := r.UpsertPost(&post); err != nil {
+			log.Printf("Error saving post %s: %v", *post.ID, err)
+		}
+	}
+	return posts, nil
+}
+
+// UpsertPost inserts a post coming from Sanity or updates the stored copy, keyed on the
+// Sanity _id, restoring it if it had been unpublished. View counts are left untouched.
+// When the post's path changes the new path is recorded in post_paths next to the old ones,
+// and post_authors and post_tags are updated to the post's current authors and tags.
+func (r *PostRepository) UpsertPost(post *models.Post) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		var existing models.Post
+		err := tx.Unscoped().Where("id = ?", *post.ID).Limit(1).Find(&existing).Error
 		if err != nil {
-			if err.Error() == "duplicate key value violates unique constraint" {
-				log.Printf("Skipping duplicate post: %s", *post.ID)
-				continue
+			return fmt.Errorf("failed to fetch post: %w", err)
+		}
+		if existing.ID != nil && hasPagePath(existing) {
+			// Posts stored before post_paths existed only know their current path
+			if err := recordPostPath(tx, existing.ID, postPagePath(existing)); err != nil {
+				return err
 			}
-			log.Printf("Error creating post: %v", err)
 		}
+
+		err = tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"title", "slug", "author_id", "main_category", "sub_category", "type", "published_at", "deleted_at"}),
+		}).Create(post).Error
+		if err != nil {
+			return fmt.Errorf("failed to upsert post: %w", err)
+		}
+
+		if hasPagePath(*post) {
+			if err := recordPostPath(tx, post.ID, postPagePath(*post)); err != nil {
+				return err
+			}
+		}
+		if err := syncPostAuthors(tx, *post); err != nil {
+			return err
+		}
+		if err := syncPostTags(tx, *post); err != nil {
+			return err
+		}
+		if existing.ID != nil && hasPagePath(existing) && hasPagePath(*post) && postPagePath(existing) != postPagePath(*post) {
+			log.Printf("Post %s moved from %s to %s", *post.ID, postPagePath(existing), postPagePath(*post))
+		}
+		return nil
+	})
+}
+
+// SyncPost re-reads a post from Sanity and stores it. A post that is no longer published
+// in Sanity (unpublished or deleted) is soft-deleted, and nil is returned for it.
+func (r *PostRepository) SyncPost(id string) (*models.Post, error) {
+	result, err := r.Sanity.Query(`*[`+sanityPostFilter+` && _id == $id][0]`+sanityPostProjection, map[string]interface{}{
+		"id": id,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query Sanity: %w", err)
 	}
-	return posts, nil
+
+	var post *models.Post
+	if err := result.Unmarshal(&post); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal Sanity result: %w", err)
+	}
+	if post == nil {
+		return nil, r.DeletePost(id)
+	}
+	if err := r.UpsertPost(post); err != nil {
+		return nil, err
+	}
+	return post, nil
 }
 
-func (r *PostRepository) GetPostsFromDatabase() ([]models.Post, error) {
-	var posts []models.Post
-	err := r.DB.Find(&posts).Error
+// DeletePost soft-deletes a post so it drops out of listings and reports while its
+// stored views are kept
+func (r *PostRepository) DeletePost(id string) error {
+	if err := r.DB.Where("id = ?", id).Delete(&models.Post{}).Error; err != nil {
+		return fmt.Errorf("failed to delete post %s: %w", id, err)
+	}
+	return nil
+}
+
+func recordPostPath(tx *gorm.DB, postID *string, path string) error {
+	err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.PostPath{PostID: postID, Path: path}).Error
+	if err != nil {
+		return fmt.Errorf("failed to record path %s: %w", path, err)
+	}
+	return nil
+}
+
+// PostWithMetrics is a post together with its engagement metrics
+type PostWithMetrics struct {
+	models.Post
+	Metrics models.PostMetrics `json:"metrics" gorm:"embedded"`
+}
+
+// PostQuery filters, sorts and paginates GetPostsFromDatabase. Empty fields are ignored.
+type PostQuery struct {
+	AuthorID      string
+	MainCategory  string
+	SubCategory   string
+	Type          string
+	PublishedFrom string
+	PublishedTo   string
+	// Window limits the engagement metrics to each post's first N days (a range type)
+	Window string
+	// SortBy is a key of postSortColumns, publishedAt by default
+	SortBy string
+	Asc    bool
+	Limit  int
+	// Cursor is the NextCursor of the previous page
+	Cursor string
+}
+
+// PostPage is one page of GetPostsFromDatabase results
+type PostPage struct {
+	Posts      []PostWithMetrics
+	Total      int64
+	NextCursor string
+}
+
+// postSortColumns maps the sortable JSON fields of a post to their columns
+var postSortColumns = map[string]string{
+	"publishedAt":        "published_at",
+	"yesterdayViews":     "yesterday_views",
+	"lastSevenDaysViews": "last_seven_days_views",
+	"last14DaysViews":    "last_14_days_views",
+	"last30DaysViews":    "last_30_days_views",
+	"last90DaysViews":    "last_90_days_views",
+	"last180DaysViews":   "last_180_days_views",
+	"last365DaysViews":   "last_365_days_views",
+}
+
+// ErrInvalidPostQuery is wrapped by GetPostsFromDatabase errors caused by bad input
+var ErrInvalidPostQuery = errors.New("invalid post query")
+
+// postCursor is the position after the last post of a page: its sort value and ID
+type postCursor struct {
+	Value interface{} `json:"v"`
+	ID    string      `json:"id"`
+}
+
+// GetPostsFromDatabase returns a page of posts with their engagement metrics. When
+// query.Window is a range type the metrics cover each post's first N days, otherwise
+// every stored day. Pages are keyset-paginated on the sort column and post ID.
+func (r *PostRepository) GetPostsFromDatabase(query PostQuery) (*PostPage, error) {
+	sortBy := query.SortBy
+	if sortBy == "" {
+		sortBy = "publishedAt"
+	}
+	sortColumn, ok := postSortColumns[sortBy]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown sort field %s", ErrInvalidPostQuery, sortBy)
+	}
+	if query.Limit <= 0 {
+		query.Limit = 50
+	}
+
+	filtered := r.DB.Model(&models.Post{})
+	if query.AuthorID != "" {
+		filtered = filtered.Where("EXISTS (SELECT 1 FROM post_authors pa WHERE pa.post_id = posts.id AND pa.author_id = ?)", query.AuthorID)
+	}
+	if query.MainCategory != "" {
+		filtered = filtered.Where("posts.main_category = ?", query.MainCategory)
+	}
+	if query.SubCategory != "" {
+		filtered = filtered.Where("posts.sub_category = ?", query.SubCategory)
+	}
+	if query.Type != "" {
+		filtered = filtered.Where("posts.type = ?", query.Type)
+	}
+	if query.PublishedFrom != "" {
+		filtered = filtered.Where("DATE(posts.published_at) >= ?", query.PublishedFrom)
+	}
+	if query.PublishedTo != "" {
+		filtered = filtered.Where("DATE(posts.published_at) <= ?", query.PublishedTo)
+	}
+
+	page := &PostPage{}
+	if err := filtered.Session(&gorm.Session{}).Count(&page.Total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count posts: %w", err)
+	}
+
+	direction, comparison := "DESC", "<"
+	if query.Asc {
+		direction, comparison = "ASC", ">"
+	}
+	paged := filtered.Session(&gorm.Session{})
+	if query.Cursor != "" {
+		cursor, err := decodePostCursor(query.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		paged = paged.Where(fmt.Sprintf("(posts.%s, posts.id) %s (?, ?)", sortColumn, comparison), cursor.Value, cursor.ID)
+	}
+
+	err := paged.
+		Select(`posts.*,
+			COALESCE(m.users, 0) AS users,
+			COALESCE(m.sessions, 0) AS sessions,
+			COALESCE(m.engagement_duration, 0) AS engagement_duration,
+			COALESCE(m.average_session_duration, 0) AS average_session_duration,
+			COALESCE(m.engagement_rate, 0) AS engagement_rate,
+			COALESCE(m.bounce_rate, 0) AS bounce_rate`).
+		Joins("LEFT JOIN (?) m ON m.post_id = posts.id", r.postMetricsQuery(utils.GetDaysFromRangeType(query.Window))).
+		Order(fmt.Sprintf("posts.%s %s, posts.id %s", sortColumn, direction, direction)).
+		Limit(query.Limit + 1).
+		Scan(&page.Posts).Error
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch posts from database: %w", err)
 	}
-	return posts, nil
+
+	if len(page.Posts) > query.Limit {
+		page.Posts = page.Posts[:query.Limit]
+		last := page.Posts[len(page.Posts)-1]
+		page.NextCursor, err = encodePostCursor(postSortValue(last.Post, sortBy), *last.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+func postSortValue(post models.Post, sortBy string) interface{} {
+	switch sortBy {
+	case "yesterdayViews":
+		return post.YesterdayViews
+	case "lastSevenDaysViews":
+		return post.LastSevenDaysViews
+	case "last14DaysViews":
+		return post.Last14DaysViews
+	case "last30DaysViews":
+		return post.Last30DaysViews
+	case "last90DaysViews":
+		return post.Last90DaysViews
+	case "last180DaysViews":
+		return post.Last180DaysViews
+	case "last365DaysViews":
+		return post.Last365DaysViews
+	default:
+		return post.PublishedAt
+	}
+}
+
+func encodePostCursor(value interface{}, id string) (string, error) {
+	raw, err := json.Marshal(postCursor{Value: value, ID: id})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func decodePostCursor(encoded string) (*postCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidPostQuery)
+	}
+	var cursor postCursor
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	// Keep view counts as exact integers instead of float64
+	decoder.UseNumber()
+	if err := decoder.Decode(&cursor); err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidPostQuery)
+	}
+	if number, ok := cursor.Value.(json.Number); ok {
+		if cursor.Value, err = number.Int64(); err != nil {
+			return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidPostQuery)
+		}
+	}
+	return &cursor, nil
+}
+
+// postPagePath builds the GA pagePath a post is served under
+func postPagePath(post models.Post) string {
+	return "/" + *post.MainCategory + "/" + *post.SubCategory + "/" + *post.Slug
+}
+
+// hasPagePath reports whether a post has everything postPagePath needs
+func hasPagePath(post models.Post) bool {
+	return post.Slug != nil && post.MainCategory != nil && post.SubCategory != nil
+}
+
+// postPaths holds every pagePath a set of posts has been served under
+type postPaths struct {
+	// paths lists each distinct path once, for GA filters
+	paths      []string
+	byPost     map[string][]string
+	postByPath map[string]*string
+	// normalize is the GA path normalization; a post keeps one path per normalized form,
+	// since GA reports every variant of that form under each of them
+	normalize  func(string) string
+	normalized map[string]map[string]bool
+}
+
+func newPostPaths(normalize func(string) string) *postPaths {
+	return &postPaths{
+		byPost:     make(map[string][]string),
+		postByPath: make(map[string]*string),
+		normalize:  normalize,
+		normalized: make(map[string]map[string]bool),
+	}
+}
+
+// add records a path of a post, skipping paths already taken by another post and paths
+// normalizing like one the post already has
+func (p *postPaths) add(postID *string, path string) {
+	if _, ok := p.postByPath[path]; ok {
+		return
+	}
+	key := p.normalize(path)
+	if p.normalized[*postID][key] {
+		return
+	}
+	if p.normalized[*postID] == nil {
+		p.normalized[*postID] = make(map[string]bool)
+	}
+	p.normalized[*postID][key] = true
+	p.paths = append(p.paths, path)
+	p.byPost[*postID] = append(p.byPost[*postID], path)
+	p.postByPath[path] = postID
 }
 
-func (r *PostRepository) GetAnalyticsData(dateRange string, posts []models.Post) ([]models.Post, error) {
-	slugs := make([]string, len(posts))
-	for i, post := range posts {
-		slugs[i] = "/" + *post.MainCategory + "/" + *post.SubCategory + "/" + *post.Slug
+// loadPostPaths collects the current path of each post plus the older ones in post_paths.
+// normalize is the GA path normalization, e.g. analytics.Client.NormalizePath.
+func loadPostPaths(db *gorm.DB, normalize func(string) string, posts []models.Post) (*postPaths, error) {
+	p := newPostPaths(normalize)
+
+	ids := make([]string, 0, len(posts))
+	for _, post := range posts {
+		if hasPagePath(post) {
+			p.add(post.ID, postPagePath(post))
+		}
+		ids = append(ids, *post.ID)
+	}
+
+	// Chunked to stay well below Postgres's bind parameter limit
+	for start := 0; start < len(ids); start += postIDChunkSize {
+		end := start + postIDChunkSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		var history []models.PostPath
+		if err := db.Where("post_id IN ?", ids[start:end]).Find(&history).Error; err != nil {
+			return nil, fmt.Errorf("failed to fetch post paths: %w", err)
+		}
+		for _, path := range history {
+			p.add(path.PostID, path.Path)
+		}
+	}
+	return p, nil
+}
+
+// sum adds up the values of every path of a post
+func (p *postPaths) sum(postID string, values map[string]int64) int64 {
+	var total int64
+	for _, path := range p.byPost[postID] {
+		total += values[path]
+	}
+	return total
+}
+
+// GetAnalyticsData fetches GA views for several windows in one batched request and stores
+// them in each window's column. postsByRange maps a range type to the posts whose first
+// N days are exactly that range, i.e. the posts published N days ago.
+func (r *PostRepository) GetAnalyticsData(postsByRange map[string][]models.Post) ([]models.Post, error) {
+	var windows []analytics.DateWindow
+	var allPosts []models.Post
+	for _, rangeType := range utils.GetRangeTypes() {
+		posts := postsByRange[rangeType]
+		if len(posts) == 0 {
+			continue
+		}
+		startDate, endDate := utils.GetDateRange(rangeType)
+		windows = append(windows, analytics.DateWindow{Name: rangeType, StartDate: startDate, EndDate: endDate})
+		allPosts = append(allPosts, posts...)
+	}
+	if len(windows) == 0 {
+		return nil, nil
+	}
+
+	// Query every path each post has had so views survive slug and category changes
+	paths, err := loadPostPaths(r.DB, r.Analytics.NormalizePath, allPosts)
+	if err != nil {
+		return nil, err
 	}
 
-	pageViews, err := r.Analytics.GetPageViews(dateRange, slugs)
+	windowViews, metadata, err := r.Analytics.GetPageViewsForWindows(windows, paths.paths)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get page views: %w", err)
 	}
+	if metadata.Partial() {
+		log.Printf("Partial GA data for closing windows: %+v", metadata)
+	}
 
-	fieldName := utils.GetDBFieldName(dateRange)
-	for i, post := range posts {
-		slug := "/" + *post.MainCategory + "/" + *post.SubCategory + "/" + *post.Slug
-		if views, ok := pageViews[slug]; ok {
+	var updated []models.Post
+	for _, window := range windows {
+		fieldName := utils.GetDBFieldName(window.Name)
+		posts := postsByRange[window.Name]
+		for i, post := range posts {
+			views := paths.sum(*post.ID, windowViews[window.Name])
 			err := r.DB.Model(&posts[i]).Update(fieldName, views).Error
 			if err != nil {
 				log.Printf("Error updating post %s: %v", *post.ID, err)
+				continue
+			}
+			if err := r.markWindowFromGA(*post.ID, window); err != nil {
+				log.Printf("Error marking %s window of post %s: %v", window.Name, *post.ID, err)
 			}
+			updated = append(updated, posts[i])
 		}
 	}
 
-	return posts, nil
+	return updated, nil
 }
 
-func (r *PostRepository) UpdateYesterdayViews() ([]models.Post, error) {
-	var dbPosts []models.Post
-	yesterday := utils.GetDateNDaysAgo(2)
-	err := r.DB.Where("DATE(published_at) >= ?", yesterday).Where("slug is not NULL").Find(&dbPosts).Error
+// markWindowFromGA records that a post's window holds GA's total for the closed window,
+// so later daily refreshes don't replace it with a sum of daily views
+func (r *PostRepository) markWindowFromGA(postID string, window analytics.DateWindow) error {
+	end, err := utils.ParseDate(window.EndDate)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch posts for yesterday: %w", err)
+		return err
 	}
-	if len(dbPosts) == 0 {
-		log.Println("No posts found for yesterday")
-		return nil, nil
+	sync := models.PostWindowSync{
+		PostID:        &postID,
+		RangeType:     window.Name,
+		SyncedThrough: end,
+		Source:        models.WindowSourceGA,
+	}
+	return r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "post_id"}, {Name: "range_type"}},
+		DoUpdates: clause.AssignmentColumns([]string{"synced_through", "source", "updated_at"}),
+	}).Create(&sync).Error
+}
+
+// UpdateClosedWindowViews replaces the daily-derived value of every window that closed
+// yesterday with GA's own total for the window, using a single batched GA request
+func (r *PostRepository) UpdateClosedWindowViews() ([]models.Post, error) {
+	postsByRange := make(map[string][]models.Post)
+	for _, rangeType := range utils.GetRangeTypes() {
+		posts, err := r.getPostsClosingWindow(rangeType)
+		if err != nil {
+			return nil, err
+		}
+		postsByRange[rangeType] = posts
+	}
+	return r.GetAnalyticsData(postsByRange)
+}
+
+// getPostsClosingWindow returns the posts whose window of the given type ended yesterday
+func (r *PostRepository) getPostsClosingWindow(rangeType string) ([]models.Post, error) {
+	var posts []models.Post
+	nDaysAgo := utils.GetDateNDaysAgo(utils.GetDaysFromRangeType(rangeType))
+	err := r.DB.Where("DATE(published_at) = ?", nDaysAgo).Where("slug is not NULL").Find(&posts).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch posts for %s: %w", rangeType, err)
 	}
-	
-	return r.GetAnalyticsData("yesterday", dbPosts)
+	return posts, nil
+}
+
+// UpdateYesterdayViews ingests yesterday's daily views and refreshes the yesterday window
+func (r *PostRepository) UpdateYesterdayViews() ([]models.Post, error) {
+	if _, err := r.UpdateDailyViews(utils.GetYesterdayDate()); err != nil {
+		return nil, err
+	}
+	return r.updateViewsForDateRange("yesterday")
 }
 
 func (r *PostRepository) UpdateLastSevenDaysViews() ([]models.Post, error) {
@@ -129,11 +556,24 @@ func (r *PostRepository) UpdateLast365DaysViews() ([]models.Post, error) {
 	return r.updateViewsForDateRange("last365days")
 }
 
+// updateViewsForDateRange recomputes a window column from post_daily_views, sets the GA
+// total for posts whose window closed yesterday, and returns the posts in the window
 func (r *PostRepository) updateViewsForDateRange(rangeType string) ([]models.Post, error) {
+	since := utils.GetDateNDaysAgo(utils.GetDaysFromRangeType(rangeType))
+	if err := r.refreshWindowViews(rangeType, since); err != nil {
+		return nil, err
+	}
+
+	closing, err := r.getPostsClosingWindow(rangeType)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.GetAnalyticsData(map[string][]models.Post{rangeType: closing}); err != nil {
+		return nil, err
+	}
+
 	var dbPosts []models.Post
-	nDaysAgo := utils.GetDateNDaysAgo(utils.GetDaysFromRangeType(rangeType))
-	
-	err := r.DB.Where("DATE(published_at) = ?", nDaysAgo).Where("slug is not NULL").Find(&dbPosts).Error
+	err = r.DB.Where("DATE(published_at) >= ?", since).Where("slug is not NULL").Find(&dbPosts).Error
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch posts for %s: %w", rangeType, err)
 	}
@@ -141,6 +581,5 @@ func (r *PostRepository) updateViewsForDateRange(rangeType string) ([]models.Pos
 		log.Printf("No posts found for %s", rangeType)
 		return nil, nil
 	}
-	
-	return r.GetAnalyticsData(rangeType, dbPosts)
-}
\ No newline at end of file
+	return dbPosts, nil
+}
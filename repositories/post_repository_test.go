@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPostPathsCountVariantsOnce(t *testing.T) {
+	postA, postB := "post-a", "post-b"
+	p := newPostPaths(func(path string) string {
+		return strings.ToLower(strings.TrimRight(path, "/"))
+	})
+	p.add(&postA, "/defi/lending/foo")
+	p.add(&postA, "/defi/lending/foo/")
+	p.add(&postA, "/DeFi/lending/foo")
+	p.add(&postA, "/defi/lending/old-foo")
+	// Another post served under a path normalizing like one of post A's keeps its own
+	p.add(&postB, "/Defi/lending/foo/")
+	// A path already taken stays with the first post
+	p.add(&postB, "/defi/lending/old-foo")
+
+	wantPaths := []string{"/defi/lending/foo", "/defi/lending/old-foo", "/Defi/lending/foo/"}
+	if strings.Join(p.paths, ",") != strings.Join(wantPaths, ",") {
+		t.Errorf("paths = %v, want %v", p.paths, wantPaths)
+	}
+
+	// Report rows come back keyed by each requested path, each carrying the folded total
+	views := map[string]int64{
+		"/defi/lending/foo":     10,
+		"/defi/lending/old-foo": 4,
+		"/Defi/lending/foo/":    10,
+	}
+	if got := p.sum(postA, views); got != 14 {
+		t.Errorf("sum(post A) = %d, want 14", got)
+	}
+	if got := p.sum(postB, views); got != 10 {
+		t.Errorf("sum(post B) = %d, want 10", got)
+	}
+	if got := *p.postByPath["/defi/lending/old-foo"]; got != postA {
+		t.Errorf("postByPath[old-foo] = %s, want %s", got, postA)
+	}
+}
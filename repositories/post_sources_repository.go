@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"fmt"
+	"log"
+
+	"gorm.io/gorm/clause"
+	"thedefiant.io/analytics/models"
+	"thedefiant.io/analytics/utils"
+)
+
+// TrafficSourceTotals is a post's traffic from one source/medium/channel over a period
+type TrafficSourceTotals struct {
+	Source       string `json:"source"`
+	Medium       string `json:"medium"`
+	ChannelGroup string `json:"channelGroup"`
+	Views        int64  `json:"views"`
+	Sessions     int64  `json:"sessions"`
+	Users        int64  `json:"users"`
+}
+
+// UpdateTrafficSources fetches the source / medium / channel breakdown of every published
+// post's traffic on the given date and stores it in post_traffic_sources
+func (r *PostRepository) UpdateTrafficSources(date string) ([]models.PostTrafficSource, error) {
+	var posts []models.Post
+	err := r.DB.Where("DATE(published_at) <= ?", date).Where("slug is not NULL").Find(&posts).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch posts for %s: %w", date, err)
+	}
+	if len(posts) == 0 {
+		log.Printf("No posts found for %s", date)
+		return nil, nil
+	}
+
+	paths, err := loadPostPaths(r.DB, r.Analytics.NormalizePath, posts)
+	if err != nil {
+		return nil, err
+	}
+
+	sources, metadata, err := r.Analytics.GetDailyTrafficSources(date, date, paths.paths)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get traffic sources: %w", err)
+	}
+	if metadata.Partial() {
+		log.Printf("Partial GA traffic source data for %s: %+v", date, metadata)
+	}
+
+	// Several paths of the same post fold into one row per source
+	type sourceKey struct {
+		postID, date, source, medium, channelGroup string
+	}
+	rowIndex := make(map[sourceKey]int)
+	var rows []models.PostTrafficSource
+	for _, source := range sources {
+		postID, ok := paths.postByPath[source.PagePath]
+		if !ok {
+			continue
+		}
+		key := sourceKey{*postID, source.Date, source.Source, source.Medium, source.ChannelGroup}
+		if i, ok := rowIndex[key]; ok {
+			rows[i].Views += source.Views
+			rows[i].Sessions += source.Sessions
+			rows[i].Users += source.Users
+			continue
+		}
+		day, err := utils.ParseDate(source.Date)
+		if err != nil {
+			return nil, fmt.Errorf("invalid traffic source date %q: %w", source.Date, err)
+		}
+		rowIndex[key] = len(rows)
+		rows = append(rows, models.PostTrafficSource{
+			PostID:       postID,
+			Date:         day,
+			Source:       source.Source,
+			Medium:       source.Medium,
+			ChannelGroup: source.ChannelGroup,
+			Views:        source.Views,
+			Sessions:     source.Sessions,
+			Users:        source.Users,
+		})
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	err = r.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{
+			{Name: "post_id"}, {Name: "date"}, {Name: "source"}, {Name: "medium"}, {Name: "channel_group"},
+		},
+		DoUpdates: clause.AssignmentColumns([]string{"views", "sessions", "users", "updated_at"}),
+	}).CreateInBatches(&rows, 500).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to store traffic sources: %w", err)
+	}
+	return rows, nil
+}
+
+// GetPostTrafficSources returns a post's traffic per source/medium/channel between two
+// dates (inclusive), largest first
+func (r *PostRepository) GetPostTrafficSources(postID, from, to string) ([]TrafficSourceTotals, error) {
+	var sources []TrafficSourceTotals
+	err := r.DB.Model(&models.PostTrafficSource{}).
+		Select("source, medium, channel_group, SUM(views) AS views, SUM(sessions) AS sessions, SUM(users) AS users").
+		Where("post_id = ? AND date BETWEEN ? AND ?", postID, from, to).
+		Group("source, medium, channel_group").
+		Order("views desc").
+		Scan(&sources).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch traffic sources for post %s: %w", postID, err)
+	}
+	return sources, nil
+}
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm"
+	"thedefiant.io/analytics/models"
+	"thedefiant.io/analytics/services/analytics"
+)
+
+const (
+	// realtimeCacheTTL is how long a realtime snapshot is served before GA is asked again
+	realtimeCacheTTL = time.Minute
+	// realtimeMinutes is the realtime window; standard GA properties allow up to 30 minutes
+	realtimeMinutes = 29
+	// defaultSiteName is the site name GA page titles end with unless GA_SITE_NAME is set
+	defaultSiteName = "The Defiant"
+)
+
+type RealtimeRepository struct {
+	DB        *gorm.DB
+	Analytics *analytics.Client
+	// SiteName is stripped, with its separator, from the end of page titles
+	SiteName string
+
+	// mu guards the cached snapshot; refreshes run outside it, one at a time through group
+	mu        sync.Mutex
+	topPosts  []RealtimePost
+	fetchedAt time.Time
+	group     singleflight.Group
+}
+
+type realtimeSnapshot struct {
+	topPosts  []RealtimePost
+	fetchedAt time.Time
+}
+
+// RealtimePost is a post with its traffic over the last 30 minutes
+type RealtimePost struct {
+	ID           *string   `json:"id"`
+	Title        *string   `json:"title"`
+	Slug         *string   `json:"slug"`
+	MainCategory *string   `json:"mainCategory"`
+	SubCategory  *string   `json:"subCategory"`
+	PublishedAt  time.Time `json:"publishedAt"`
+	AuthorID     *string   `json:"authorId"`
+	AuthorName   *string   `json:"authorName"`
+	ActiveUsers  int64     `json:"activeUsers"`
+	Views        int64     `json:"views"`
+}
+
+func NewRealtimeRepository(db *gorm.DB, analyticsClient *analytics.Client) *RealtimeRepository {
+	siteName := os.Getenv("GA_SITE_NAME")
+	if siteName == "" {
+		siteName = defaultSiteName
+	}
+	return &RealtimeRepository{
+		DB:        db,
+		Analytics: analyticsClient,
+		SiteName:  siteName,
+	}
+}
+
+// GetTopPosts returns the posts with the most active users in the last 30 minutes. Results
+// are cached for realtimeCacheTTL, and callers arriving while the cache is refreshed wait
+// for that one realtime GA request instead of sending their own.
+func (r *RealtimeRepository) GetTopPosts() ([]RealtimePost, time.Time, error) {
+	r.mu.Lock()
+	if r.topPosts != nil && time.Since(r.fetchedAt) < realtimeCacheTTL {
+		topPosts, fetchedAt := r.topPosts, r.fetchedAt
+		r.mu.Unlock()
+		return topPosts, fetchedAt, nil
+	}
+	r.mu.Unlock()
+
+	result, err, _ := r.group.Do("top-posts", func() (interface{}, error) {
+		topPosts, err := r.fetchTopPosts()
+		if err != nil {
+			return nil, err
+		}
+		snapshot := realtimeSnapshot{topPosts: topPosts, fetchedAt: time.Now()}
+		r.mu.Lock()
+		r.topPosts, r.fetchedAt = snapshot.topPosts, snapshot.fetchedAt
+		r.mu.Unlock()
+		return snapshot, nil
+	})
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	snapshot := result.(realtimeSnapshot)
+	return snapshot.topPosts, snapshot.fetchedAt, nil
+}
+
+// fetchTopPosts runs the realtime report and maps its pages to posts. Realtime reports
+// don't expose pagePath, so pages are matched on their title (unifiedScreenName), either
+// as is or with the trailing " | SiteName" or " - SiteName" removed.
+func (r *RealtimeRepository) fetchTopPosts() ([]RealtimePost, error) {
+	rows, err := r.Analytics.RunRealtimeReport(analytics.RealtimeQuery{
+		MinutesAgo: realtimeMinutes,
+		Dimensions: []string{"unifiedScreenName"},
+		Metrics:    []string{"activeUsers", "screenPageViews"},
+		Limit:      100,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return []RealtimePost{}, nil
+	}
+
+	titles := make([]string, 0, 2*len(rows))
+	for _, row := range rows {
+		screenName := row.Dimensions["unifiedScreenName"]
+		titles = append(titles, screenName, r.realtimeTitle(screenName))
+	}
+
+	var posts []RealtimePost
+	err = r.DB.Model(&models.Post{}).
+		Select("posts.id, posts.title, posts.slug, posts.main_category, posts.sub_category, posts.published_at, posts.author_id, authors.name AS author_name").
+		Joins("LEFT JOIN authors ON authors.id = posts.author_id").
+		Where("posts.title IN ?", titles).
+		Scan(&posts).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch realtime posts: %w", err)
+	}
+	postsByTitle := make(map[string]RealtimePost, len(posts))
+	for _, post := range posts {
+		if post.Title != nil {
+			postsByTitle[*post.Title] = post
+		}
+	}
+
+	// Rows are ordered by active users, so the result keeps GA's ranking
+	topPosts := make([]RealtimePost, 0, len(rows))
+	for _, row := range rows {
+		screenName := row.Dimensions["unifiedScreenName"]
+		post, ok := postsByTitle[screenName]
+		if !ok {
+			post, ok = postsByTitle[r.realtimeTitle(screenName)]
+		}
+		if !ok {
+			continue
+		}
+		post.ActiveUsers = row.Int("activeUsers")
+		post.Views = row.Int("screenPageViews")
+		topPosts = append(topPosts, post)
+	}
+	return topPosts, nil
+}
+
+// realtimeTitle strips the site name GA appends to page titles, along with the "|" or dash
+// separating it from the title. Titles that don't end with the site name are kept whole.
+func (r *RealtimeRepository) realtimeTitle(screenName string) string {
+	title := strings.TrimSpace(screenName)
+	if r.SiteName == "" {
+		return title
+	}
+	rest, ok := strings.CutSuffix(title, r.SiteName)
+	if !ok {
+		return title
+	}
+	rest = strings.TrimSpace(rest)
+	for _, separator := range []string{"|", "-", "–", "—"} {
+		if stripped, ok := strings.CutSuffix(rest, separator); ok && strings.TrimSpace(stripped) != "" {
+			return strings.TrimSpace(stripped)
+		}
+	}
+	return title
+}
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"thedefiant.io/analytics/models"
+)
+
+// sanityPageSize is how many documents are read from Sanity per reconciliation query
+const sanityPageSize = 1000
+
+// PostDiff lists how the posts table differs from the published posts in Sanity
+type PostDiff struct {
+	// Missing are published in Sanity but were never stored
+	Missing []models.Post `json:"missing"`
+	// Restored are published in Sanity but soft-deleted here
+	Restored []models.Post `json:"restored"`
+	// Changed are stored with a different title, path, authors, tags, type or publish date
+	Changed []models.Post `json:"changed"`
+	// Removed are stored here but deleted or unpublished in Sanity
+	Removed []models.Post `json:"removed"`
+}
+
+// Empty reports whether the posts table matches Sanity
+func (d *PostDiff) Empty() bool {
+	return len(d.Missing) == 0 && len(d.Restored) == 0 && len(d.Changed) == 0 && len(d.Removed) == 0
+}
+
+// ReconcilePosts compares every published blog and sponsor document in Sanity with the
+// posts table. Unless dryRun is set, missing, restored and changed posts are upserted and
+// removed ones are soft-deleted. The returned diff describes what was (or would be) done.
+func (r *PostRepository) ReconcilePosts(dryRun bool) (*PostDiff, error) {
+	sanityPosts, err := r.fetchAllSanityPosts()
+	if err != nil {
+		return nil, err
+	}
+	// An empty result is far more likely a Sanity problem than every post being deleted
+	if len(sanityPosts) == 0 {
+		return nil, errors.New("no posts returned by Sanity, refusing to reconcile")
+	}
+
+	var stored []models.Post
+	if err := r.DB.Unscoped().Find(&stored).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch posts: %w", err)
+	}
+	storedByID := make(map[string]models.Post, len(stored))
+	for _, post := range stored {
+		storedByID[*post.ID] = post
+	}
+	storedAuthors, err := getAllPostAuthorIDs(r.DB)
+	if err != nil {
+		return nil, err
+	}
+	storedTags, err := getAllPostTagSlugs(r.DB)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &PostDiff{
+		Missing:  make([]models.Post, 0),
+		Restored: make([]models.Post, 0),
+		Changed:  make([]models.Post, 0),
+		Removed:  make([]models.Post, 0),
+	}
+	published := make(map[string]bool, len(sanityPosts))
+	for _, post := range sanityPosts {
+		published[*post.ID] = true
+		existing, ok := storedByID[*post.ID]
+		switch {
+		case !ok:
+			diff.Missing = append(diff.Missing, post)
+		case existing.DeletedAt.Valid:
+			diff.Restored = append(diff.Restored, post)
+		case postChanged(existing, post),
+			!equalStrings(storedAuthors[*post.ID], postAuthorIDs(post)),
+			!equalStrings(storedTags[*post.ID], postTagSlugs(post)):
+			diff.Changed = append(diff.Changed, post)
+		}
+	}
+	for _, post := range stored {
+		if !post.DeletedAt.Valid && !published[*post.ID] {
+			diff.Removed = append(diff.Removed, post)
+		}
+	}
+
+	if dryRun {
+		return diff, nil
+	}
+
+	for _, group := range [][]models.Post{diff.Missing, diff.Restored, diff.Changed} {
+		for i := range group {
+			if err := r.UpsertPost(&group[i]); err != nil {
+				return diff, fmt.Errorf("failed to save post %s: %w", *group[i].ID, err)
+			}
+		}
+	}
+	for _, post := range diff.Removed {
+		if err := r.DeletePost(*post.ID); err != nil {
+			return diff, err
+		}
+	}
+	log.Printf("Reconciled posts with Sanity: %d missing, %d restored, %d changed, %d removed",
+		len(diff.Missing), len(diff.Restored), len(diff.Changed), len(diff.Removed))
+	return diff, nil
+}
+
+// fetchAllSanityPosts pages through every published post in Sanity ordered by _id
+func (r *PostRepository) fetchAllSanityPosts() ([]models.Post, error) {
+	query := fmt.Sprintf(`*[%s && _id > $after] | order(_id) [0...%d]%s`, sanityPostFilter, sanityPageSize, sanityPostProjection)
+
+	var posts []models.Post
+	after := ""
+	for {
+		result, err := r.Sanity.Query(query, map[string]interface{}{"after": after})
+		if err != nil {
+			return nil, fmt.Errorf("failed to query Sanity: %w", err)
+		}
+		var page []models.Post
+		if err := result.Unmarshal(&page); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal Sanity result: %w", err)
+		}
+		posts = append(posts, page...)
+		if len(page) < sanityPageSize {
+			return posts, nil
+		}
+		after = *page[len(page)-1].ID
+	}
+}
+
+// postChanged reports whether Sanity's copy of a post differs from the stored one in any
+// field UpsertPost writes
+func postChanged(stored, current models.Post) bool {
+	return !equalString(stored.Title, current.Title) ||
+		!equalString(stored.Slug, current.Slug) ||
+		!equalString(stored.AuthorId, current.AuthorId) ||
+		!equalString(stored.MainCategory, current.MainCategory) ||
+		!equalString(stored.SubCategory, current.SubCategory) ||
+		!equalString(stored.Type, current.Type) ||
+		!stored.PublishedAt.Equal(current.PublishedAt)
+}
+
+func equalString(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
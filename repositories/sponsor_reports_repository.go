@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"thedefiant.io/analytics/models"
+	"thedefiant.io/analytics/utils"
+)
+
+// DefaultSponsorWindowDays is the contracted delivery window used when none is given
+const DefaultSponsorWindowDays = 30
+
+// ErrSponsorPostNotFound is returned when no sponsored post has the requested ID
+var ErrSponsorPostNotFound = errors.New("sponsored post not found")
+
+// ChannelViews is the views a post received through one default channel group
+type ChannelViews struct {
+	ChannelGroup string  `json:"channelGroup"`
+	Views        int64   `json:"views"`
+	Share        float64 `json:"share"`
+}
+
+// SponsorReport is what a sponsored post delivered over its contracted window, which
+// starts on the publish date and lasts WindowDays. Complete is false while the window is
+// still running, in which case the numbers run up to yesterday.
+type SponsorReport struct {
+	PostID       *string   `json:"postId"`
+	Title        *string   `json:"title"`
+	Slug         *string   `json:"slug"`
+	MainCategory *string   `json:"mainCategory"`
+	SubCategory  *string   `json:"subCategory"`
+	PublishedAt  time.Time `json:"publishedAt"`
+	WindowDays   int       `json:"windowDays"`
+	WindowStart  string    `json:"windowStart"`
+	WindowEnd    string    `json:"windowEnd"`
+	Complete     bool      `json:"complete"`
+	Views        int64     `json:"views"`
+	Users        int64     `json:"users"`
+	Sessions     int64     `json:"sessions"`
+	// EngagementDuration is the total engagement time in seconds
+	EngagementDuration float64 `json:"engagementDuration"`
+	// AverageEngagementTime is the engagement time per user in seconds
+	AverageEngagementTime float64        `json:"averageEngagementTime"`
+	EngagementRate        float64        `json:"engagementRate"`
+	Channels              []ChannelViews `json:"channels"`
+}
+
+// GetSponsorReports reports every sponsored post published between two dates over its
+// contracted window, newest first. Posts without a stored window use windowDays. Only
+// stored daily data is used.
+func (r *PostRepository) GetSponsorReports(publishedFrom, publishedTo string, windowDays int) ([]SponsorReport, error) {
+	if windowDays <= 0 {
+		windowDays = DefaultSponsorWindowDays
+	}
+
+	var rows []struct {
+		ID                 *string
+		Title              *string
+		Slug               *string
+		MainCategory       *string
+		SubCategory        *string
+		PublishedAt        time.Time
+		WindowDays         int
+		Views              int64
+		Users              int64
+		Sessions           int64
+		EngagedSessions    int64
+		EngagementDuration float64
+	}
+	err := r.DB.Model(&models.Post{}).
+		Select(`posts.id, posts.title, posts.slug, posts.main_category, posts.sub_category, posts.published_at,
+			COALESCE(posts.sponsor_window_days, ?) AS window_days,
+			COALESCE(SUM(d.views), 0) AS views,
+			COALESCE(SUM(d.users), 0) AS users,
+			COALESCE(SUM(d.sessions), 0) AS sessions,
+			COALESCE(SUM(d.engaged_sessions), 0) AS engaged_sessions,
+			COALESCE(SUM(d.engagement_duration), 0) AS engagement_duration`, windowDays).
+		Joins(`LEFT JOIN post_daily_views d ON d.post_id = posts.id
+			AND d.date >= DATE(posts.published_at)
+			AND d.date < DATE(posts.published_at) + COALESCE(posts.sponsor_window_days, ?::int)`, windowDays).
+		Where("posts.type = ?", models.PostTypeSponsor).
+		Where("DATE(posts.published_at) BETWEEN ? AND ?", publishedFrom, publishedTo).
+		Group("posts.id").
+		Order("posts.published_at desc").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sponsored posts: %w", err)
+	}
+
+	channels, err := r.getSponsorChannels(publishedFrom, publishedTo, windowDays)
+	if err != nil {
+		return nil, err
+	}
+
+	yesterday := utils.GetYesterdayDate()
+	reports := make([]SponsorReport, len(rows))
+	for i, row := range rows {
+		start := time.Date(row.PublishedAt.Year(), row.PublishedAt.Month(), row.PublishedAt.Day(), 0, 0, 0, 0, time.UTC)
+		end := utils.FormatDate(start.AddDate(0, 0, row.WindowDays-1))
+		reports[i] = SponsorReport{
+			PostID:             row.ID,
+			Title:              row.Title,
+			Slug:               row.Slug,
+			MainCategory:       row.MainCategory,
+			SubCategory:        row.SubCategory,
+			PublishedAt:        row.PublishedAt,
+			WindowDays:         row.WindowDays,
+			WindowStart:        utils.FormatDate(start),
+			WindowEnd:          end,
+			Complete:           end <= yesterday,
+			Views:              row.Views,
+			Users:              row.Users,
+			Sessions:           row.Sessions,
+			EngagementDuration: row.EngagementDuration,
+			Channels:           make([]ChannelViews, 0),
+		}
+		if row.Users > 0 {
+			reports[i].AverageEngagementTime = row.EngagementDuration / float64(row.Users)
+		}
+		if row.Sessions > 0 {
+			reports[i].EngagementRate = float64(row.EngagedSessions) / float64(row.Sessions)
+		}
+
+		var sourcedViews int64
+		for _, channel := range channels[*row.ID] {
+			sourcedViews += channel.Views
+		}
+		for _, channel := range channels[*row.ID] {
+			if sourcedViews > 0 {
+				channel.Share = float64(channel.Views) / float64(sourcedViews)
+			}
+			reports[i].Channels = append(reports[i].Channels, channel)
+		}
+	}
+	return reports, nil
+}
+
+// getSponsorChannels returns the channel mix of each sponsored post over its window
+// (windowDays when it has none stored), keyed by post ID, largest channel first
+func (r *PostRepository) getSponsorChannels(publishedFrom, publishedTo string, windowDays int) (map[string][]ChannelViews, error) {
+	var rows []struct {
+		PostID       string
+		ChannelGroup string
+		Views        int64
+	}
+	err := r.DB.Table("post_traffic_sources s").
+		Select("s.post_id, s.channel_group, SUM(s.views) AS views").
+		Joins("JOIN posts ON posts.id = s.post_id AND posts.deleted_at IS NULL").
+		Where("posts.type = ?", models.PostTypeSponsor).
+		Where("DATE(posts.published_at) BETWEEN ? AND ?", publishedFrom, publishedTo).
+		Where("s.date >= DATE(posts.published_at) AND s.date < DATE(posts.published_at) + COALESCE(posts.sponsor_window_days, ?::int)", windowDays).
+		Group("s.post_id, s.channel_group").
+		Order("s.post_id, views desc").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sponsored post channels: %w", err)
+	}
+
+	channels := make(map[string][]ChannelViews)
+	for _, row := range rows {
+		channels[row.PostID] = append(channels[row.PostID], ChannelViews{ChannelGroup: row.ChannelGroup, Views: row.Views})
+	}
+	return channels, nil
+}
+
+// SetSponsorWindow stores the contracted delivery window of a sponsored post
+func (r *PostRepository) SetSponsorWindow(id string, days int) (*models.Post, error) {
+	var post models.Post
+	err := r.DB.Where("id = ? AND type = ?", id, models.PostTypeSponsor).First(&post).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSponsorPostNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sponsored post %s: %w", id, err)
+	}
+	if err := r.DB.Model(&post).Update("sponsor_window_days", days).Error; err != nil {
+		return nil, fmt.Errorf("failed to store window of sponsored post %s: %w", id, err)
+	}
+	return &post, nil
+}
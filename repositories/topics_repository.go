@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"thedefiant.io/analytics/models"
+)
+
+// ErrTopicNotFound is returned when no tag has the requested slug
+var ErrTopicNotFound = errors.New("topic not found")
+
+type TopicRepository struct {
+	DB *gorm.DB
+}
+
+func NewTopicRepository(db *gorm.DB) *TopicRepository {
+	return &TopicRepository{DB: db}
+}
+
+// postTagSlugs returns the distinct tag slugs of a post coming from Sanity, sorted
+func postTagSlugs(post models.Post) []string {
+	seen := make(map[string]bool, len(post.Tags))
+	slugs := make([]string, 0, len(post.Tags))
+	for _, tag := range post.Tags {
+		if tag.Slug == "" || seen[tag.Slug] {
+			continue
+		}
+		seen[tag.Slug] = true
+		slugs = append(slugs, tag.Slug)
+	}
+	sort.Strings(slugs)
+	return slugs
+}
+
+// syncPostTags makes post_tags list exactly the tags of the post, creating tags that are
+// new. Posts read without their tags are left alone.
+func syncPostTags(tx *gorm.DB, post models.Post) error {
+	if post.Tags == nil {
+		return nil
+	}
+	slugs := postTagSlugs(post)
+	if post.TagRefCount > 0 && len(slugs) == 0 {
+		log.Printf("Post %s has %d entries in its Sanity tags and topics arrays but none resolved to a tag", *post.ID, post.TagRefCount)
+	}
+
+	var tagIDs []uint
+	if len(slugs) > 0 {
+		names := make(map[string]string, len(post.Tags))
+		for _, tag := range post.Tags {
+			if tag.Name != "" {
+				names[tag.Slug] = tag.Name
+			}
+		}
+		tags := make([]models.Tag, len(slugs))
+		for i, slug := range slugs {
+			tags[i] = models.Tag{Slug: slug, Name: names[slug]}
+			if tags[i].Name == "" {
+				tags[i].Name = slug
+			}
+		}
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "slug"}},
+			DoUpdates: clause.AssignmentColumns([]string{"name", "updated_at"}),
+		}).Create(&tags).Error
+		if err != nil {
+			return fmt.Errorf("failed to store tags of post %s: %w", *post.ID, err)
+		}
+		if err := tx.Model(&models.Tag{}).Where("slug IN ?", slugs).Pluck("id", &tagIDs).Error; err != nil {
+			return fmt.Errorf("failed to fetch tags of post %s: %w", *post.ID, err)
+		}
+	}
+
+	stale := tx.Where("post_id = ?", *post.ID)
+	if len(tagIDs) > 0 {
+		stale = stale.Where("tag_id NOT IN ?", tagIDs)
+	}
+	if err := stale.Delete(&models.PostTag{}).Error; err != nil {
+		return fmt.Errorf("failed to remove old tags of post %s: %w", *post.ID, err)
+	}
+	if len(tagIDs) == 0 {
+		return nil
+	}
+
+	rows := make([]models.PostTag, len(tagIDs))
+	for i, id := range tagIDs {
+		rows[i] = models.PostTag{PostID: post.ID, TagID: id}
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error; err != nil {
+		return fmt.Errorf("failed to tag post %s: %w", *post.ID, err)
+	}
+	return nil
+}
+
+// getAllPostTagSlugs returns the stored tag slugs of every post, sorted
+func getAllPostTagSlugs(db *gorm.DB) (map[string][]string, error) {
+	var rows []struct {
+		PostID string
+		Slug   string
+	}
+	err := db.Table("post_tags").
+		Select("post_tags.post_id, tags.slug").
+		Joins("JOIN tags ON tags.id = post_tags.tag_id").
+		Order("post_tags.post_id, tags.slug").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch post tags: %w", err)
+	}
+	slugs := make(map[string][]string)
+	for _, row := range rows {
+		slugs[row.PostID] = append(slugs[row.PostID], row.Slug)
+	}
+	return slugs, nil
+}
+
+// TopicTotals is a tag's views over a period
+type TopicTotals struct {
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+	// PostCount is the number of posts filed under the tag that had views in the period
+	PostCount int64 `json:"postCount"`
+	Views     int64 `json:"views"`
+	// ViewsPerPost is Views divided by PostCount
+	ViewsPerPost float64 `json:"viewsPerPost"`
+}
+
+// TopicPost is a post filed under a tag with its views over a period
+type TopicPost struct {
+	models.Post
+	Views int64 `json:"views"`
+}
+
+// TopicDetail is one tag's views over a period, with the posts that earned them
+type TopicDetail struct {
+	TopicTotals
+	Posts []TopicPost `json:"posts"`
+}
+
+// GetTopics returns every tag's views between two dates, most viewed first
+func (r *TopicRepository) GetTopics(from, to string) ([]TopicTotals, error) {
+	topics := make([]TopicTotals, 0)
+	err := r.topicTotalsQuery(from, to).
+		Group("tags.id").
+		Order("views desc, tags.slug").
+		Scan(&topics).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate topic views: %w", err)
+	}
+	for i := range topics {
+		topics[i].setViewsPerPost()
+	}
+	return topics, nil
+}
+
+// GetTopic returns one tag's views between two dates and its posts, most viewed first
+func (r *TopicRepository) GetTopic(slug, from, to string) (*TopicDetail, error) {
+	var tag models.Tag
+	err := r.DB.Where("slug = ?", slug).First(&tag).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTopicNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch topic %s: %w", slug, err)
+	}
+
+	detail := &TopicDetail{TopicTotals: TopicTotals{Slug: tag.Slug, Name: tag.Name}}
+	err = r.topicTotalsQuery(from, to).
+		Where("tags.id = ?", tag.ID).
+		Group("tags.id").
+		Scan(&detail.TopicTotals).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate views of topic %s: %w", slug, err)
+	}
+	detail.setViewsPerPost()
+
+	detail.Posts = make([]TopicPost, 0)
+	err = r.DB.Model(&models.Post{}).
+		Select("posts.*, COALESCE(SUM(d.views), 0) AS views").
+		Joins("JOIN post_tags pt ON pt.post_id = posts.id AND pt.tag_id = ?", tag.ID).
+		Joins("LEFT JOIN post_daily_views d ON d.post_id = posts.id AND d.date BETWEEN ? AND ?", from, to).
+		Where("DATE(posts.published_at) <= ?", to).
+		Group("posts.id").
+		Order("views desc, posts.published_at desc").
+		Scan(&detail.Posts).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch posts of topic %s: %w", slug, err)
+	}
+	return detail, nil
+}
+
+// topicTotalsQuery sums post_daily_views per tag between two dates
+func (r *TopicRepository) topicTotalsQuery(from, to string) *gorm.DB {
+	return r.DB.Table("tags").
+		Select("tags.slug, tags.name, COUNT(DISTINCT d.post_id) AS post_count, COALESCE(SUM(d.views), 0) AS views").
+		Joins("JOIN post_tags pt ON pt.tag_id = tags.id").
+		Joins("JOIN posts ON posts.id = pt.post_id AND posts.deleted_at IS NULL").
+		Joins("LEFT JOIN post_daily_views d ON d.post_id = posts.id AND d.date BETWEEN ? AND ?", from, to)
+}
+
+func (t *TopicTotals) setViewsPerPost() {
+	if t.PostCount > 0 {
+		t.ViewsPerPost = float64(t.Views) / float64(t.PostCount)
+	}
+}
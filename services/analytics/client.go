@@ -2,20 +2,25 @@ package analytics
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"strconv"
+	"time"
 
 	"golang.org/x/oauth2/google"
 	analyticsdata "google.golang.org/api/analyticsdata/v1beta"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
 	"thedefiant.io/analytics/utils"
 )
 
 // Client wraps the Google Analytics Data API client
 type Client struct {
-	service *analyticsdata.Service
-	propID  string
+	service    *analyticsdata.Service
+	propID     string
+	normalizer *PathNormalizer
 }
 
 // NewClient creates a new Google Analytics Data API client
@@ -39,61 +44,276 @@ func NewClient() (*Client, error) {
 	}
 
 	return &Client{
-		service: service,
-		propID:  propID,
+		service:    service,
+		propID:     propID,
+		normalizer: NewPathNormalizerFromEnv(),
 	}, nil
 }
 
+// NormalizePath returns the canonical form of a GA pagePath, as used to key report rows
+func (c *Client) NormalizePath(path string) string {
+	return c.normalizer.Normalize(path)
+}
+
 // GetPageViews retrieves page views for the given slugs within the specified date range
-func (c *Client) GetPageViews(dateRange string, slugs []string) (map[string]int64, error) {
+func (c *Client) GetPageViews(dateRange string, slugs []string) (map[string]int64, ReportMetadata, error) {
 	startDate, endDate := utils.GetDateRange(dateRange)
-	fmt.Println(c)
-
-	req := &analyticsdata.RunReportRequest{
-		Property: "properties/" + c.propID,
-		DateRanges: []*analyticsdata.DateRange{
-			{StartDate: startDate, EndDate: endDate},
-		},
-		Metrics: []*analyticsdata.Metric{
-			{Name: "screenPageViews"},
-		},
-		Dimensions: []*analyticsdata.Dimension{
-			{Name: "pagePath"},
-		},
-		DimensionFilter: &analyticsdata.FilterExpression{
-			Filter: &analyticsdata.Filter{
-				FieldName: "pagePath",
-				InListFilter: &analyticsdata.InListFilter{
-					Values: slugs,
-				},
+	return c.GetPageViewsBetween(startDate, endDate, slugs)
+}
+
+// GetPageViewsBetween retrieves page views for the given slugs between two explicit dates (inclusive)
+func (c *Client) GetPageViewsBetween(startDate, endDate string, slugs []string) (map[string]int64, ReportMetadata, error) {
+	rows, metadata, err := c.runPathReport(slugs, func() *analyticsdata.RunReportRequest {
+		return &analyticsdata.RunReportRequest{
+			DateRanges: []*analyticsdata.DateRange{
+				{StartDate: startDate, EndDate: endDate},
 			},
-		},
-	}
-	resp, err := c.service.Properties.RunReport(req.Property, req).Do()
-	
+			Metrics: []*analyticsdata.Metric{
+				{Name: "screenPageViews"},
+			},
+			Dimensions: []*analyticsdata.Dimension{
+				{Name: "pagePath"},
+			},
+		}
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to run analytics report: %w", err)
-	}
-	var analyticsData []struct {
-		PagePath       string
-		ScreenPageViews int64
+		return nil, metadata, err
 	}
-	for _, row := range resp.Rows {
+
+	viewCounts := make(map[string]int64)
+	for _, row := range rows {
 		pagePath := row.DimensionValues[0].Value
 		screenPageViews, _ := strconv.ParseInt(row.MetricValues[0].Value, 10, 64)
+		viewCounts[pagePath] += screenPageViews
+	}
+	return viewCounts, metadata, nil
+}
+
+// PageMetrics holds the GA engagement metrics of a page. Every field is additive, so
+// metrics of several days or paths can be summed before rates are derived from them.
+type PageMetrics struct {
+	Views           int64 `json:"views"`
+	Users           int64 `json:"users"`
+	Sessions        int64 `json:"sessions"`
+	EngagedSessions int64 `json:"engagedSessions"`
+	// EngagementDuration is GA's userEngagementDuration in seconds
+	EngagementDuration float64 `json:"engagementDuration"`
+	// SessionDuration is averageSessionDuration multiplied back by sessions, in seconds
+	SessionDuration float64 `json:"sessionDuration"`
+}
+
+// pageMetricNames are the GA metrics parsed by parsePageMetrics, in order
+var pageMetricNames = []string{
+	"screenPageViews",
+	"totalUsers",
+	"sessions",
+	"engagedSessions",
+	"userEngagementDuration",
+	"averageSessionDuration",
+}
+
+func pageMetrics() []*analyticsdata.Metric {
+	metrics := make([]*analyticsdata.Metric, len(pageMetricNames))
+	for i, name := range pageMetricNames {
+		metrics[i] = &analyticsdata.Metric{Name: name}
+	}
+	return metrics
+}
+
+func parsePageMetrics(row *analyticsdata.Row) PageMetrics {
+	value := func(i int) float64 {
+		v, _ := strconv.ParseFloat(row.MetricValues[i].Value, 64)
+		return v
+	}
+	sessions := int64(value(2))
+	return PageMetrics{
+		Views:              int64(value(0)),
+		Users:              int64(value(1)),
+		Sessions:           sessions,
+		EngagedSessions:    int64(value(3)),
+		EngagementDuration: value(4),
+		SessionDuration:    value(5) * float64(sessions),
+	}
+}
 
-		analyticsData = append(analyticsData, struct {
-			PagePath       string
-			ScreenPageViews int64
-		}{
-			PagePath:       pagePath,
-			ScreenPageViews: screenPageViews,
+// Add sums another set of metrics into m
+func (m *PageMetrics) Add(other PageMetrics) {
+	m.Views += other.Views
+	m.Users += other.Users
+	m.Sessions += other.Sessions
+	m.EngagedSessions += other.EngagedSessions
+	m.EngagementDuration += other.EngagementDuration
+	m.SessionDuration += other.SessionDuration
+}
+
+// GetDailyPageMetrics retrieves views, users, sessions and engagement for the given slugs
+// broken down by day. The result is keyed by date ("2006-01-02") and then by page path.
+func (c *Client) GetDailyPageMetrics(startDate, endDate string, slugs []string) (map[string]map[string]PageMetrics, ReportMetadata, error) {
+	rows, metadata, err := c.runPathReport(slugs, func() *analyticsdata.RunReportRequest {
+		return &analyticsdata.RunReportRequest{
+			DateRanges: []*analyticsdata.DateRange{
+				{StartDate: startDate, EndDate: endDate},
+			},
+			Metrics: pageMetrics(),
+			Dimensions: []*analyticsdata.Dimension{
+				{Name: "date"},
+				{Name: "pagePath"},
+			},
+		}
+	})
+	if err != nil {
+		return nil, metadata, fmt.Errorf("failed to run daily analytics report: %w", err)
+	}
+
+	dailyMetrics := make(map[string]map[string]PageMetrics)
+	for _, row := range rows {
+		// GA returns dates as YYYYMMDD
+		day, err := time.Parse("20060102", row.DimensionValues[0].Value)
+		if err != nil {
+			return nil, metadata, fmt.Errorf("failed to parse report date %q: %w", row.DimensionValues[0].Value, err)
+		}
+		date := utils.FormatDate(day)
+		pagePath := row.DimensionValues[1].Value
+
+		if dailyMetrics[date] == nil {
+			dailyMetrics[date] = make(map[string]PageMetrics)
+		}
+		metrics := dailyMetrics[date][pagePath]
+		metrics.Add(parsePageMetrics(row))
+		dailyMetrics[date][pagePath] = metrics
+	}
+	return dailyMetrics, metadata, nil
+}
+
+// TrafficSource is the traffic a page received from one source/medium/channel on one day
+type TrafficSource struct {
+	Date         string
+	PagePath     string
+	Source       string
+	Medium       string
+	ChannelGroup string
+	Views        int64
+	Sessions     int64
+	Users        int64
+}
+
+// GetDailyTrafficSources retrieves the source, medium and default channel group of the
+// traffic the given slugs received, broken down by day
+func (c *Client) GetDailyTrafficSources(startDate, endDate string, slugs []string) ([]TrafficSource, ReportMetadata, error) {
+	rows, metadata, err := c.RunReport(ReportQuery{
+		StartDate:  startDate,
+		EndDate:    endDate,
+		Dimensions: []string{"date", "pagePath", "sessionSource", "sessionMedium", "sessionDefaultChannelGroup"},
+		Metrics:    []string{"screenPageViews", "sessions", "totalUsers"},
+		Paths:      slugs,
+	})
+	if err != nil {
+		return nil, metadata, fmt.Errorf("failed to run traffic source report: %w", err)
+	}
+
+	sources := make([]TrafficSource, 0, len(rows))
+	for _, row := range rows {
+		date, err := row.Date()
+		if err != nil {
+			return nil, metadata, err
+		}
+		sources = append(sources, TrafficSource{
+			Date:         date,
+			PagePath:     row.Dimensions["pagePath"],
+			Source:       row.Dimensions["sessionSource"],
+			Medium:       row.Dimensions["sessionMedium"],
+			ChannelGroup: row.Dimensions["sessionDefaultChannelGroup"],
+			Views:        row.Int("screenPageViews"),
+			Sessions:     row.Int("sessions"),
+			Users:        row.Int("totalUsers"),
 		})
 	}
-	viewCounts := make(map[string]int64)
-    for _, data := range analyticsData {
-        viewCounts[data.PagePath] = data.ScreenPageViews
-    }
-	return viewCounts, nil
+	return sources, metadata, nil
 }
 
+// DateWindow is a named date range, e.g. {"last7days", "7daysAgo", "yesterday"}
+type DateWindow struct {
+	Name      string
+	StartDate string
+	EndDate   string
+}
+
+// GetPageViewsForWindows retrieves page views for the given slugs in several date windows
+// at once. Windows are packed into as few RunReport requests as GA allows and those are
+// sent together through BatchRunReports. The result is keyed by window name, then page path.
+func (c *Client) GetPageViewsForWindows(windows []DateWindow, slugs []string) (map[string]map[string]int64, ReportMetadata, error) {
+	windowViews := make(map[string]map[string]int64)
+	for _, window := range windows {
+		windowViews[window.Name] = make(map[string]int64)
+	}
+	metadata := ReportMetadata{SamplingRatio: 1}
+
+	batchSize := c.normalizer.batchSize()
+	for start := 0; start < len(slugs); start += batchSize {
+		end := start + batchSize
+		if end > len(slugs) {
+			end = len(slugs)
+		}
+
+		var reqs []*analyticsdata.RunReportRequest
+		for ws := 0; ws < len(windows); ws += maxDateRangesPerReport {
+			we := ws + maxDateRangesPerReport
+			if we > len(windows) {
+				we = len(windows)
+			}
+			dateRanges := make([]*analyticsdata.DateRange, 0, we-ws)
+			for _, window := range windows[ws:we] {
+				dateRanges = append(dateRanges, &analyticsdata.DateRange{
+					Name:      window.Name,
+					StartDate: window.StartDate,
+					EndDate:   window.EndDate,
+				})
+			}
+			reqs = append(reqs, &analyticsdata.RunReportRequest{
+				DateRanges: dateRanges,
+				Metrics: []*analyticsdata.Metric{
+					{Name: "screenPageViews"},
+				},
+				Dimensions: []*analyticsdata.Dimension{
+					{Name: "pagePath"},
+				},
+				DimensionFilter: c.normalizer.Filter(slugs[start:end]),
+			})
+		}
+
+		reports, batchMetadata, err := c.runBatchReports(reqs)
+		if err != nil {
+			return nil, metadata, err
+		}
+		metadata.merge(batchMetadata)
+
+		canonical := c.normalizer.canonicalPaths(slugs[start:end])
+		for i, report := range reports {
+			pathIdx := dimensionIndex(report, "pagePath")
+			report.Rows = c.normalizer.foldPaths(report.Rows, pathIdx, canonical)
+			// GA only adds the dateRange dimension when a request has several ranges
+			rangeIdx := dimensionIndex(report, "dateRange")
+			for _, row := range report.Rows {
+				name := reqs[i].DateRanges[0].Name
+				if rangeIdx >= 0 {
+					name = row.DimensionValues[rangeIdx].Value
+				}
+				if windowViews[name] == nil {
+					continue
+				}
+				screenPageViews, _ := strconv.ParseInt(row.MetricValues[0].Value, 10, 64)
+				windowViews[name][row.DimensionValues[pathIdx].Value] += screenPageViews
+			}
+		}
+	}
+	return windowViews, metadata, nil
+}
+
+// IsQuotaError reports whether err was caused by GA rejecting a request for exhausted quota
+func IsQuotaError(err error) bool {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.Code == http.StatusTooManyRequests
+}
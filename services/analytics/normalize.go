@@ -0,0 +1,239 @@
+package analytics
+
+import (
+	"log"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	analyticsdata "google.golang.org/api/analyticsdata/v1beta"
+)
+
+// PathNormalizer folds the pagePath variants GA records for a page (trailing slashes,
+// query strings, case, AMP versions, hostname prefixes) into one canonical path
+type PathNormalizer struct {
+	// ExactMatch filters reports with an exact InListFilter and skips normalization
+	ExactMatch bool
+	// CaseSensitive keeps the case of paths instead of lowercasing them
+	CaseSensitive bool
+	// KeepTrailingSlash keeps a trailing slash instead of stripping it
+	KeepTrailingSlash bool
+	// KeepQuery keeps query strings and fragments instead of stripping them
+	KeepQuery bool
+	// Hosts are hostnames that may prefix a path, e.g. "thedefiant.io". A scheme followed
+	// by any host is always stripped.
+	Hosts []string
+	// AMPSuffixes are stripped from the end of a path, e.g. "/amp"
+	AMPSuffixes []string
+	// AMPPrefixes are stripped from the start of a path, e.g. "/amp"
+	AMPPrefixes []string
+}
+
+// NewPathNormalizerFromEnv builds a normalizer from the environment. Everything is
+// normalized by default; GA_PATH_MATCH=exact turns it off, GA_PATH_CASE_SENSITIVE,
+// GA_PATH_KEEP_TRAILING_SLASH and GA_PATH_KEEP_QUERY opt out of single steps, and
+// GA_PATH_HOSTS, GA_PATH_AMP_SUFFIXES and GA_PATH_AMP_PREFIXES take comma-separated lists.
+func NewPathNormalizerFromEnv() *PathNormalizer {
+	return &PathNormalizer{
+		ExactMatch:        os.Getenv("GA_PATH_MATCH") == "exact",
+		CaseSensitive:     os.Getenv("GA_PATH_CASE_SENSITIVE") == "true",
+		KeepTrailingSlash: os.Getenv("GA_PATH_KEEP_TRAILING_SLASH") == "true",
+		KeepQuery:         os.Getenv("GA_PATH_KEEP_QUERY") == "true",
+		Hosts:             envList("GA_PATH_HOSTS", "thedefiant.io,www.thedefiant.io"),
+		AMPSuffixes:       envList("GA_PATH_AMP_SUFFIXES", "/amp"),
+		AMPPrefixes:       envList("GA_PATH_AMP_PREFIXES", "/amp"),
+	}
+}
+
+// envList splits a comma-separated environment variable, falling back to def when unset
+func envList(key, def string) []string {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		value = def
+	}
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// Normalize returns the canonical form of a GA pagePath
+func (n *PathNormalizer) Normalize(path string) string {
+	if n.ExactMatch {
+		return path
+	}
+
+	if i := strings.Index(path, "://"); i >= 0 {
+		path = path[i+3:]
+		if slash := strings.Index(path, "/"); slash >= 0 {
+			path = path[slash:]
+		} else {
+			path = "/"
+		}
+	}
+	for _, host := range n.Hosts {
+		if rest, ok := strings.CutPrefix(path, host); ok && (rest == "" || rest[0] == '/' || rest[0] == '?') {
+			path = "/" + strings.TrimPrefix(rest, "/")
+			break
+		}
+	}
+
+	if !n.KeepQuery {
+		if i := strings.IndexAny(path, "?#"); i >= 0 {
+			path = path[:i]
+		}
+	}
+	if !n.CaseSensitive {
+		path = strings.ToLower(path)
+	}
+
+	path = strings.TrimRight(path, "/")
+	for _, suffix := range n.AMPSuffixes {
+		if trimmed, ok := strings.CutSuffix(path, n.fold(suffix)); ok {
+			path = strings.TrimRight(trimmed, "/")
+			break
+		}
+	}
+	for _, prefix := range n.AMPPrefixes {
+		if rest, ok := strings.CutPrefix(path, n.fold(prefix)); ok && strings.HasPrefix(rest, "/") {
+			path = rest
+			break
+		}
+	}
+
+	if path == "" {
+		return "/"
+	}
+	if n.KeepTrailingSlash && path != "/" {
+		return path + "/"
+	}
+	return path
+}
+
+func (n *PathNormalizer) fold(s string) string {
+	if n.CaseSensitive {
+		return s
+	}
+	return strings.ToLower(s)
+}
+
+// pattern returns one regular expression matching every variant of the given canonical
+// paths that Normalize folds back into them
+func (n *PathNormalizer) pattern(paths []string) string {
+	hosts := []string{`https?://[^/]+`}
+	for _, host := range n.Hosts {
+		hosts = append(hosts, regexp.QuoteMeta(host))
+	}
+	quoted := make([]string, len(paths))
+	for i, path := range paths {
+		quoted[i] = regexp.QuoteMeta(strings.TrimRight(path, "/"))
+	}
+
+	var b strings.Builder
+	b.WriteString("^(" + strings.Join(hosts, "|") + ")?")
+	b.WriteString(optionalGroup(n.AMPPrefixes))
+	b.WriteString("(" + strings.Join(quoted, "|") + ")/*")
+	b.WriteString(optionalGroup(n.AMPSuffixes))
+	b.WriteString("/*")
+	if !n.KeepQuery {
+		b.WriteString(`([?#].*)?`)
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+func optionalGroup(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = regexp.QuoteMeta(value)
+	}
+	return "(" + strings.Join(quoted, "|") + ")?"
+}
+
+// batchSize is how many paths go into one Filter. A regular expression grows with every
+// path, so those batches are kept much smaller than exact lists.
+func (n *PathNormalizer) batchSize() int {
+	if n.ExactMatch {
+		return pathBatchSize
+	}
+	return regexPathBatchSize
+}
+
+// Filter returns the dimension filter matching the given canonical paths: an exact
+// InListFilter when normalization is off, otherwise a single regular expression with one
+// alternative per path
+func (n *PathNormalizer) Filter(paths []string) *analyticsdata.FilterExpression {
+	if n.ExactMatch {
+		return &analyticsdata.FilterExpression{
+			Filter: &analyticsdata.Filter{
+				FieldName: "pagePath",
+				InListFilter: &analyticsdata.InListFilter{
+					Values: paths,
+				},
+			},
+		}
+	}
+
+	return &analyticsdata.FilterExpression{
+		Filter: &analyticsdata.Filter{
+			FieldName: "pagePath",
+			StringFilter: &analyticsdata.StringFilter{
+				MatchType:     "FULL_REGEXP",
+				Value:         n.pattern(paths),
+				CaseSensitive: n.CaseSensitive,
+			},
+		},
+	}
+}
+
+// canonicalPaths maps the normalized form of each requested path back to the paths
+// themselves, so report rows come back keyed the way the caller asked for them. Paths that
+// normalize to the same key can't be told apart in GA; they are logged and each of them
+// is credited with all of the views.
+func (n *PathNormalizer) canonicalPaths(paths []string) map[string][]string {
+	canonical := make(map[string][]string, len(paths))
+	for _, path := range paths {
+		key := n.Normalize(path)
+		if !slices.Contains(canonical[key], path) {
+			canonical[key] = append(canonical[key], path)
+		}
+	}
+	for key, variants := range canonical {
+		if len(variants) > 1 {
+			log.Printf("GA paths %v all normalize to %s, crediting each with its views", variants, key)
+		}
+	}
+	return canonical
+}
+
+// foldPaths rewrites the pagePath dimension (at index idx) of each row to its canonical
+// path, copying the row for every requested path sharing it. Rows whose path normalizes
+// to none of the requested paths, e.g. a longer slug sharing the requested one as a
+// prefix, keep their normalized path.
+func (n *PathNormalizer) foldPaths(rows []*analyticsdata.Row, idx int, canonical map[string][]string) []*analyticsdata.Row {
+	if idx < 0 || n.ExactMatch {
+		return rows
+	}
+	folded := make([]*analyticsdata.Row, 0, len(rows))
+	for _, row := range rows {
+		normalized := n.Normalize(row.DimensionValues[idx].Value)
+		paths, ok := canonical[normalized]
+		if !ok {
+			paths = []string{normalized}
+		}
+		for _, path := range paths {
+			copied := *row
+			copied.DimensionValues = append([]*analyticsdata.DimensionValue(nil), row.DimensionValues...)
+			copied.DimensionValues[idx] = &analyticsdata.DimensionValue{Value: path}
+			folded = append(folded, &copied)
+		}
+	}
+	return folded
+}
@@ -0,0 +1,134 @@
+package analytics
+
+import (
+	"regexp"
+	"testing"
+
+	analyticsdata "google.golang.org/api/analyticsdata/v1beta"
+)
+
+func defaultNormalizer() *PathNormalizer {
+	return &PathNormalizer{
+		Hosts:       []string{"thedefiant.io", "www.thedefiant.io"},
+		AMPSuffixes: []string{"/amp"},
+		AMPPrefixes: []string{"/amp"},
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name       string
+		normalizer *PathNormalizer
+		path       string
+		want       string
+	}{
+		{"canonical", defaultNormalizer(), "/defi/lending/foo", "/defi/lending/foo"},
+		{"trailing slash", defaultNormalizer(), "/defi/lending/foo/", "/defi/lending/foo"},
+		{"query and fragment", defaultNormalizer(), "/defi/lending/foo?utm_source=x#top", "/defi/lending/foo"},
+		{"case", defaultNormalizer(), "/DeFi/Lending/Foo", "/defi/lending/foo"},
+		{"amp suffix", defaultNormalizer(), "/defi/lending/foo/amp/", "/defi/lending/foo"},
+		{"amp prefix", defaultNormalizer(), "/amp/defi/lending/foo", "/defi/lending/foo"},
+		{"scheme and host", defaultNormalizer(), "https://thedefiant.io/defi/lending/foo", "/defi/lending/foo"},
+		{"bare host", defaultNormalizer(), "www.thedefiant.io/defi/lending/foo", "/defi/lending/foo"},
+		{"host prefix of a path", defaultNormalizer(), "/thedefiant.iox/foo", "/thedefiant.iox/foo"},
+		{"root", defaultNormalizer(), "/", "/"},
+		{"exact match", &PathNormalizer{ExactMatch: true}, "/Defi/Foo/", "/Defi/Foo/"},
+		{"case sensitive", &PathNormalizer{CaseSensitive: true}, "/Defi/Foo/", "/Defi/Foo"},
+		{"keep trailing slash", &PathNormalizer{KeepTrailingSlash: true}, "/defi/foo", "/defi/foo/"},
+		{"keep query", &PathNormalizer{KeepQuery: true}, "/defi/foo?page=2", "/defi/foo?page=2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.normalizer.Normalize(tt.path); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatternMatchesVariants(t *testing.T) {
+	n := defaultNormalizer()
+	re := regexp.MustCompile("(?i)" + n.pattern([]string{"/defi/lending/foo", "/nfts/art/bar"}))
+	for path, want := range map[string]bool{
+		"/defi/lending/foo":                      true,
+		"/defi/lending/foo/":                     true,
+		"/defi/lending/foo?utm_source=x":         true,
+		"/amp/defi/lending/foo":                  true,
+		"/defi/lending/foo/amp":                  true,
+		"https://thedefiant.io/defi/lending/foo": true,
+		"/nfts/art/bar":                          true,
+		"/defi/lending/foobar":                   false,
+		"/defi/lending/foo/comments":             false,
+		"/other/defi/lending/foo":                false,
+	} {
+		if got := re.MatchString(path); got != want {
+			t.Errorf("pattern matches %q = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func row(path string, views string) *analyticsdata.Row {
+	return &analyticsdata.Row{
+		DimensionValues: []*analyticsdata.DimensionValue{{Value: path}},
+		MetricValues:    []*analyticsdata.MetricValue{{Value: views}},
+	}
+}
+
+func TestFoldPaths(t *testing.T) {
+	n := defaultNormalizer()
+	tests := []struct {
+		name      string
+		requested []string
+		rows      []*analyticsdata.Row
+		want      map[string]string
+	}{
+		{
+			name:      "variant folds into the requested path",
+			requested: []string{"/defi/lending/foo"},
+			rows:      []*analyticsdata.Row{row("/DeFi/lending/foo/?ref=x", "7")},
+			want:      map[string]string{"/defi/lending/foo": "7"},
+		},
+		{
+			name:      "requested path keeps its own form",
+			requested: []string{"/defi/lending/Foo/"},
+			rows:      []*analyticsdata.Row{row("/defi/lending/foo", "3")},
+			want:      map[string]string{"/defi/lending/Foo/": "3"},
+		},
+		{
+			name:      "colliding requested paths each get the row",
+			requested: []string{"/defi/lending/foo", "/DEFI/lending/foo"},
+			rows:      []*analyticsdata.Row{row("/defi/lending/foo", "5")},
+			want:      map[string]string{"/defi/lending/foo": "5", "/DEFI/lending/foo": "5"},
+		},
+		{
+			name:      "unrequested path keeps its normalized form",
+			requested: []string{"/defi/lending/foo"},
+			rows:      []*analyticsdata.Row{row("/defi/lending/foobar/", "2")},
+			want:      map[string]string{"/defi/lending/foobar": "2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			folded := n.foldPaths(tt.rows, 0, n.canonicalPaths(tt.requested))
+			got := make(map[string]string, len(folded))
+			for _, row := range folded {
+				got[row.DimensionValues[0].Value] = row.MetricValues[0].Value
+			}
+			if len(got) != len(tt.want) || len(folded) != len(tt.want) {
+				t.Fatalf("foldPaths = %v, want %v", got, tt.want)
+			}
+			for path, views := range tt.want {
+				if got[path] != views {
+					t.Errorf("foldPaths[%q] = %q, want %q", path, got[path], views)
+				}
+			}
+		})
+	}
+
+	// The original rows must not be rewritten in place
+	original := row("/defi/lending/foo/", "1")
+	n.foldPaths([]*analyticsdata.Row{original}, 0, n.canonicalPaths([]string{"/defi/lending/foo"}))
+	if original.DimensionValues[0].Value != "/defi/lending/foo/" {
+		t.Errorf("foldPaths rewrote the input row to %q", original.DimensionValues[0].Value)
+	}
+}
@@ -0,0 +1,59 @@
+package analytics
+
+import (
+	"fmt"
+	"strconv"
+
+	analyticsdata "google.golang.org/api/analyticsdata/v1beta"
+)
+
+// RealtimeQuery describes a GA realtime report over the last MinutesAgo minutes (at most
+// 29 for standard properties). Realtime reports support a smaller set of dimensions than
+// regular reports; pages are identified by unifiedScreenName, the page title.
+type RealtimeQuery struct {
+	MinutesAgo int64
+	Dimensions []string
+	Metrics    []string
+	Limit      int64
+}
+
+// RunRealtimeReport runs a realtime report and returns its rows keyed by dimension and metric name
+func (c *Client) RunRealtimeReport(query RealtimeQuery) ([]ReportRow, error) {
+	req := &analyticsdata.RunRealtimeReportRequest{
+		MinuteRanges: []*analyticsdata.MinuteRange{
+			{StartMinutesAgo: query.MinutesAgo, EndMinutesAgo: 0},
+		},
+		Limit: query.Limit,
+	}
+	for _, name := range query.Dimensions {
+		req.Dimensions = append(req.Dimensions, &analyticsdata.Dimension{Name: name})
+	}
+	for _, name := range query.Metrics {
+		req.Metrics = append(req.Metrics, &analyticsdata.Metric{Name: name})
+	}
+	if len(query.Metrics) > 0 {
+		req.OrderBys = []*analyticsdata.OrderBy{
+			{Metric: &analyticsdata.MetricOrderBy{MetricName: query.Metrics[0]}, Desc: true},
+		}
+	}
+
+	resp, err := c.service.Properties.RunRealtimeReport("properties/"+c.propID, req).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run realtime report: %w", err)
+	}
+
+	rows := make([]ReportRow, len(resp.Rows))
+	for i, row := range resp.Rows {
+		rows[i] = ReportRow{
+			Dimensions: make(map[string]string, len(query.Dimensions)),
+			Metrics:    make(map[string]float64, len(query.Metrics)),
+		}
+		for j, name := range query.Dimensions {
+			rows[i].Dimensions[name] = row.DimensionValues[j].Value
+		}
+		for j, name := range query.Metrics {
+			rows[i].Metrics[name], _ = strconv.ParseFloat(row.MetricValues[j].Value, 64)
+		}
+	}
+	return rows, nil
+}
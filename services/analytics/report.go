@@ -0,0 +1,272 @@
+package analytics
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	analyticsdata "google.golang.org/api/analyticsdata/v1beta"
+)
+
+const (
+	// pathBatchSize caps how many page paths go into a single exact pagePath filter
+	pathBatchSize = 250
+	// regexPathBatchSize caps how many page paths are joined into one pagePath regex
+	regexPathBatchSize = 50
+	// reportPageSize is the number of rows requested per RunReport page
+	reportPageSize = 100000
+	// maxDateRangesPerReport and maxReportsPerBatch are GA4 request limits
+	maxDateRangesPerReport = 4
+	maxReportsPerBatch     = 5
+)
+
+// ReportMetadata tells callers whether GA returned complete numbers for a report
+type ReportMetadata struct {
+	// Sampled is set when GA computed the report from a sample of events
+	Sampled bool `json:"sampled"`
+	// SamplingRatio is the share of events read when sampled (1 when not sampled)
+	SamplingRatio float64 `json:"samplingRatio"`
+	// Thresholded is set when GA withheld rows to protect user privacy
+	Thresholded bool `json:"thresholded"`
+	// DataLossFromOtherRow is set when rows were folded into "(other)"
+	DataLossFromOtherRow bool `json:"dataLossFromOtherRow"`
+}
+
+// Partial reports whether any of the numbers may be incomplete
+func (m ReportMetadata) Partial() bool {
+	return m.Sampled || m.Thresholded || m.DataLossFromOtherRow
+}
+
+// merge folds the metadata of another page or batch into m
+func (m *ReportMetadata) merge(other ReportMetadata) {
+	if other.Sampled && (!m.Sampled || other.SamplingRatio < m.SamplingRatio) {
+		m.SamplingRatio = other.SamplingRatio
+	}
+	m.Sampled = m.Sampled || other.Sampled
+	m.Thresholded = m.Thresholded || other.Thresholded
+	m.DataLossFromOtherRow = m.DataLossFromOtherRow || other.DataLossFromOtherRow
+}
+
+func newReportMetadata(meta *analyticsdata.ResponseMetaData) ReportMetadata {
+	metadata := ReportMetadata{SamplingRatio: 1}
+	if meta == nil {
+		return metadata
+	}
+	metadata.Thresholded = meta.SubjectToThresholding
+	metadata.DataLossFromOtherRow = meta.DataLossFromOtherRow
+	for _, sampling := range meta.SamplingMetadatas {
+		if sampling.SamplingSpaceSize == 0 {
+			continue
+		}
+		ratio := float64(sampling.SamplesReadCount) / float64(sampling.SamplingSpaceSize)
+		if !metadata.Sampled || ratio < metadata.SamplingRatio {
+			metadata.SamplingRatio = ratio
+		}
+		metadata.Sampled = true
+	}
+	return metadata
+}
+
+// runReport runs a report and pages through every row from req.Offset on using Limit/Offset,
+// stopping after maxRows rows unless it is 0
+func (c *Client) runReport(req *analyticsdata.RunReportRequest, maxRows int64) ([]*analyticsdata.Row, ReportMetadata, error) {
+	req.Property = "properties/" + c.propID
+	req.Limit = reportPageSize
+	if maxRows > 0 && maxRows < reportPageSize {
+		req.Limit = maxRows
+	}
+
+	var rows []*analyticsdata.Row
+	metadata := ReportMetadata{SamplingRatio: 1}
+	for {
+		resp, err := c.service.Properties.RunReport(req.Property, req).Do()
+		if err != nil {
+			return nil, metadata, fmt.Errorf("failed to run analytics report: %w", err)
+		}
+		rows = append(rows, resp.Rows...)
+		metadata.merge(newReportMetadata(resp.Metadata))
+
+		req.Offset += int64(len(resp.Rows))
+		if len(resp.Rows) == 0 || req.Offset >= resp.RowCount {
+			break
+		}
+		if maxRows > 0 && int64(len(rows)) >= maxRows {
+			rows = rows[:maxRows]
+			break
+		}
+	}
+	return rows, metadata, nil
+}
+
+// runPathReport runs a report filtered to the given page paths, splitting the paths into
+// batches so large lists don't exceed GA's request limits. build returns the request for
+// everything but the pagePath filter. Variants of a path are folded into the path as given.
+func (c *Client) runPathReport(paths []string, build func() *analyticsdata.RunReportRequest) ([]*analyticsdata.Row, ReportMetadata, error) {
+	var rows []*analyticsdata.Row
+	metadata := ReportMetadata{SamplingRatio: 1}
+	batchSize := c.normalizer.batchSize()
+	for start := 0; start < len(paths); start += batchSize {
+		end := start + batchSize
+		if end > len(paths) {
+			end = len(paths)
+		}
+
+		req := build()
+		req.DimensionFilter = c.normalizer.Filter(paths[start:end])
+		batchRows, batchMetadata, err := c.runReport(req, 0)
+		if err != nil {
+			return nil, metadata, err
+		}
+		batchRows = c.normalizer.foldPaths(batchRows, requestDimensionIndex(req, "pagePath"), c.normalizer.canonicalPaths(paths[start:end]))
+		rows = append(rows, batchRows...)
+		metadata.merge(batchMetadata)
+	}
+	return rows, metadata, nil
+}
+
+// runBatchReports sends the reports through BatchRunReports, maxReportsPerBatch at a time,
+// and pages through any report whose rows didn't fit in the first page
+func (c *Client) runBatchReports(reqs []*analyticsdata.RunReportRequest) ([]*analyticsdata.RunReportResponse, ReportMetadata, error) {
+	property := "properties/" + c.propID
+	metadata := ReportMetadata{SamplingRatio: 1}
+	var reports []*analyticsdata.RunReportResponse
+	for start := 0; start < len(reqs); start += maxReportsPerBatch {
+		end := start + maxReportsPerBatch
+		if end > len(reqs) {
+			end = len(reqs)
+		}
+		for _, req := range reqs[start:end] {
+			req.Property = property
+			req.Limit = reportPageSize
+		}
+
+		batch := &analyticsdata.BatchRunReportsRequest{Requests: reqs[start:end]}
+		resp, err := c.service.Properties.BatchRunReports(property, batch).Do()
+		if err != nil {
+			return nil, metadata, fmt.Errorf("failed to run batched analytics reports: %w", err)
+		}
+
+		for i, report := range resp.Reports {
+			metadata.merge(newReportMetadata(report.Metadata))
+			if int64(len(report.Rows)) < report.RowCount {
+				req := *reqs[start+i]
+				req.Offset = int64(len(report.Rows))
+				rest, restMetadata, err := c.runReport(&req, 0)
+				if err != nil {
+					return nil, metadata, err
+				}
+				report.Rows = append(report.Rows, rest...)
+				metadata.merge(restMetadata)
+			}
+			reports = append(reports, report)
+		}
+	}
+	return reports, metadata, nil
+}
+
+// dimensionIndex returns the position of a dimension in a report's rows, or -1
+func dimensionIndex(report *analyticsdata.RunReportResponse, name string) int {
+	for i, header := range report.DimensionHeaders {
+		if header.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// requestDimensionIndex returns the position of a dimension in a request, or -1
+func requestDimensionIndex(req *analyticsdata.RunReportRequest, name string) int {
+	for i, dimension := range req.Dimensions {
+		if dimension.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// ReportQuery describes a generic GA report. When Paths is set the report is filtered to
+// those page paths, split into batches the filter can hold. A pagePath dimension always comes
+// back normalized, or as the requested path its variant folds into.
+type ReportQuery struct {
+	StartDate  string
+	EndDate    string
+	Dimensions []string
+	Metrics    []string
+	Paths      []string
+	// OrderBy is a metric the rows are sorted on, largest first
+	OrderBy string
+	// Limit caps the number of rows of a report without Paths, 0 for all of them
+	Limit int64
+}
+
+// ReportRow is one row of a generic report, keyed by dimension and metric name
+type ReportRow struct {
+	Dimensions map[string]string
+	Metrics    map[string]float64
+}
+
+// Int returns a metric as an integer
+func (r ReportRow) Int(metric string) int64 {
+	return int64(r.Metrics[metric])
+}
+
+// Date returns the value of the "date" dimension as "2006-01-02"
+func (r ReportRow) Date() (string, error) {
+	day, err := time.Parse("20060102", r.Dimensions["date"])
+	if err != nil {
+		return "", fmt.Errorf("failed to parse report date %q: %w", r.Dimensions["date"], err)
+	}
+	return day.Format("2006-01-02"), nil
+}
+
+// RunReport runs a report with arbitrary dimensions and metrics, paging through all rows
+func (c *Client) RunReport(query ReportQuery) ([]ReportRow, ReportMetadata, error) {
+	build := func() *analyticsdata.RunReportRequest {
+		req := &analyticsdata.RunReportRequest{
+			DateRanges: []*analyticsdata.DateRange{
+				{StartDate: query.StartDate, EndDate: query.EndDate},
+			},
+		}
+		for _, name := range query.Dimensions {
+			req.Dimensions = append(req.Dimensions, &analyticsdata.Dimension{Name: name})
+		}
+		for _, name := range query.Metrics {
+			req.Metrics = append(req.Metrics, &analyticsdata.Metric{Name: name})
+		}
+		if query.OrderBy != "" {
+			req.OrderBys = []*analyticsdata.OrderBy{
+				{Metric: &analyticsdata.MetricOrderBy{MetricName: query.OrderBy}, Desc: true},
+			}
+		}
+		return req
+	}
+
+	var rows []*analyticsdata.Row
+	var metadata ReportMetadata
+	var err error
+	if len(query.Paths) > 0 {
+		rows, metadata, err = c.runPathReport(query.Paths, build)
+	} else {
+		req := build()
+		rows, metadata, err = c.runReport(req, query.Limit)
+		rows = c.normalizer.foldPaths(rows, requestDimensionIndex(req, "pagePath"), nil)
+	}
+	if err != nil {
+		return nil, metadata, err
+	}
+
+	reportRows := make([]ReportRow, len(rows))
+	for i, row := range rows {
+		reportRows[i] = ReportRow{
+			Dimensions: make(map[string]string, len(query.Dimensions)),
+			Metrics:    make(map[string]float64, len(query.Metrics)),
+		}
+		for j, name := range query.Dimensions {
+			reportRows[i].Dimensions[name] = row.DimensionValues[j].Value
+		}
+		for j, name := range query.Metrics {
+			reportRows[i].Metrics[name], _ = strconv.ParseFloat(row.MetricValues[j].Value, 64)
+		}
+	}
+	return reportRows, metadata, nil
+}
@@ -2,22 +2,42 @@
 package beehiiv
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
+	"math/rand"
 	"net/http"
 	"os"
 	"time"
 )
 
+const (
+	// PostsPageSize is the number of posts requested per page
+	PostsPageSize = 100
+	// maxRetryAfter is the longest Retry-After we wait for before giving up
+	maxRetryAfter = 5 * time.Minute
+)
+
 type Client struct {
 	apiKey        string
 	publicationID string
 	baseURL       string
 	httpClient    *http.Client
+	maxRetries    int
+	baseDelay     time.Duration
+	maxDelay      time.Duration
 }
 
+// PostResponse is one page of posts
 type PostResponse struct {
-	Data []Post `json:"data"`
+	Data         []Post `json:"data"`
+	Limit        int    `json:"limit"`
+	Page         int    `json:"page"`
+	TotalResults int    `json:"total_results"`
+	TotalPages   int    `json:"total_pages"`
 }
 
 type Post struct {
@@ -64,52 +84,86 @@ func NewClient() (*Client, error) {
 		httpClient: &http.Client{
 			Timeout: time.Second * 30,
 		},
+		maxRetries: 4,
+		baseDelay:  time.Second,
+		maxDelay:   30 * time.Second,
 	}, nil
 }
 
-func (c *Client) GetPosts(page int) (*PostResponse, error) {
-	endpoint := fmt.Sprintf("%s/publications/%s/posts?expand=stats&limit=100&page=%d&direction=desc&order_by=publish_date", 
+// GetPosts fetches one page of posts with their stats, newest first. Pages start at 1.
+func (c *Client) GetPosts(ctx context.Context, page int) (*PostResponse, error) {
+	endpoint := fmt.Sprintf("%s/publications/%s/posts?expand=stats&limit=%d&page=%d&direction=desc&order_by=publish_date", 
 		c.baseURL, 
 		c.publicationID,
+		PostsPageSize,
 		page,
 	)
-	
-	req, err := http.NewRequest("GET", endpoint, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("received non-200 response: %d", resp.StatusCode)
-	}
 
 	var postResp PostResponse
-	if err := json.NewDecoder(resp.Body).Decode(&postResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if err := c.get(ctx, endpoint, &postResp); err != nil {
+		return nil, fmt.Errorf("failed to fetch posts page %d: %w", page, err)
 	}
-
 	return &postResp, nil
 }
 
-func (c *Client) GetPostByID(postID string) (*Post, error) {
+func (c *Client) GetPostByID(ctx context.Context, postID string) (*Post, error) {
 	endpoint := fmt.Sprintf("%s/publications/%s/posts/%s?expand=stats", 
 		c.baseURL, 
 		c.publicationID,
 		postID,
 	)
-	
-	req, err := http.NewRequest("GET", endpoint, nil)
+
+	var postResp struct {
+		Data Post `json:"data"`
+	}
+	if err := c.get(ctx, endpoint, &postResp); err != nil {
+		return nil, fmt.Errorf("failed to fetch post %s: %w", postID, err)
+	}
+	return &postResp.Data, nil
+}
+
+// get sends a GET request and decodes the response into out. Rate limited (429), server
+// errors and failed connections are retried up to maxRetries times, waiting as long as
+// Retry-After asks or backing off exponentially when it is missing. Anything else, such
+// as a response that can't be decoded, fails right away.
+func (c *Client) get(ctx context.Context, endpoint string, out interface{}) error {
+	for attempt := 0; ; attempt++ {
+		retryable, err := c.getOnce(ctx, endpoint, out)
+		if err == nil {
+			return nil
+		}
+		if !retryable || ctx.Err() != nil || attempt >= c.maxRetries {
+			return err
+		}
+
+		var apiErr *APIError
+		wait := c.backoff(attempt)
+		if errors.As(err, &apiErr) {
+			if apiErr.RetryAfter > maxRetryAfter {
+				return err
+			}
+			if apiErr.RetryAfter > 0 {
+				wait = apiErr.RetryAfter
+			}
+		}
+		log.Printf("Beehiiv request failed (attempt %d of %d), retrying in %s: %v", attempt+1, c.maxRetries+1, wait, err)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// getOnce sends a single request. retryable is set for failed connections and for API
+// errors that are temporary.
+func (c *Client) getOnce(ctx context.Context, endpoint string, out interface{}) (retryable bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return false, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Authorization", "Bearer "+c.apiKey)
@@ -117,20 +171,28 @@ func (c *Client) GetPostByID(postID string) (*Post, error) {
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+		return true, fmt.Errorf("failed to execute request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("received non-200 response: %d", resp.StatusCode)
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		apiErr := newAPIError(resp, body)
+		return apiErr.Temporary(), apiErr
 	}
 
-	var postResp struct {
-		Data Post `json:"data"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&postResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return false, fmt.Errorf("failed to decode response: %w", err)
 	}
+	return false, nil
+}
 
-	return &postResp.Data, nil
-}
\ No newline at end of file
+// backoff is the wait before retry number attempt+1: baseDelay doubled each attempt,
+// capped at maxDelay, with up to half of it added as jitter
+func (c *Client) backoff(attempt int) time.Duration {
+	wait := c.baseDelay << attempt
+	if wait <= 0 || wait > c.maxDelay {
+		wait = c.maxDelay
+	}
+	return wait + time.Duration(rand.Int63n(int64(wait)/2+1))
+}
@@ -0,0 +1,97 @@
+package beehiiv
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func testClient(baseURL string) *Client {
+	return &Client{
+		apiKey:        "key",
+		publicationID: "pub",
+		baseURL:       baseURL,
+		httpClient:    &http.Client{Timeout: time.Second},
+		maxRetries:    2,
+		baseDelay:     time.Millisecond,
+		maxDelay:      time.Millisecond,
+	}
+}
+
+func TestGetRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses []int
+		body      string
+		wantCalls int
+		wantErr   bool
+	}{
+		{"success", []int{200}, `{"data":[]}`, 1, false},
+		{"rate limited then success", []int{429, 200}, `{"data":[]}`, 2, false},
+		{"server error then success", []int{500, 503, 200}, `{"data":[]}`, 3, false},
+		{"server errors until out of retries", []int{500, 500, 500, 500}, `{}`, 3, true},
+		{"not found is not retried", []int{404}, `{}`, 1, true},
+		{"unauthorized is not retried", []int{401}, `{}`, 1, true},
+		{"undecodable body is not retried", []int{200}, `{"data":`, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := tt.responses[calls]
+				calls++
+				if status == http.StatusTooManyRequests {
+					w.Header().Set("Retry-After", "0")
+				}
+				w.WriteHeader(status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			var out PostResponse
+			err := testClient(server.URL).get(context.Background(), server.URL+"/posts", &out)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("get() error = %v, want error %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("got %d requests, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestGetRetriesFailedConnections(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	var out PostResponse
+	err := testClient(url).get(context.Background(), url+"/posts", &out)
+	var apiErr *APIError
+	if err == nil || errors.As(err, &apiErr) {
+		t.Fatalf("get() against a closed server = %v, want a connection error", err)
+	}
+}
+
+func TestGetGivesUpOnLongRetryAfter(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	var out PostResponse
+	err := testClient(server.URL).get(context.Background(), server.URL+"/posts", &out)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("get() = %v, want the 429 error", err)
+	}
+	if calls != 1 {
+		t.Errorf("got %d requests, want 1", calls)
+	}
+}
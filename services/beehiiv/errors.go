@@ -0,0 +1,76 @@
+package beehiiv
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIError is a non-200 response from the Beehiiv API
+type APIError struct {
+	StatusCode int
+	// Message joins the messages Beehiiv returned, or holds the raw body when it was not JSON
+	Message string
+	// RetryAfter is how long Beehiiv asked us to wait, zero when it did not say
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("beehiiv: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("beehiiv: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Temporary reports whether the request may succeed if retried
+func (e *APIError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// newAPIError builds an APIError from a response and its body, which Beehiiv sends as
+// {"status": 429, "statusText": "...", "errors": [{"message": "...", "code": "..."}]}
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+
+	var payload struct {
+		StatusText string `json:"statusText"`
+		Errors     []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		apiErr.Message = strings.TrimSpace(string(body))
+		return apiErr
+	}
+	var messages []string
+	for _, e := range payload.Errors {
+		if e.Message != "" {
+			messages = append(messages, e.Message)
+		}
+	}
+	if len(messages) == 0 && payload.StatusText != "" {
+		messages = append(messages, payload.StatusText)
+	}
+	apiErr.Message = strings.Join(messages, "; ")
+	return apiErr
+}
+
+// parseRetryAfter reads a Retry-After header given either in seconds or as an HTTP date
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(strings.TrimSpace(header)); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(header); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,114 @@
+package beehiiv
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		header string
+		want   time.Duration
+	}{
+		{"missing", "", 0},
+		{"seconds", "30", 30 * time.Second},
+		{"seconds with spaces", " 5 ", 5 * time.Second},
+		{"zero seconds", "0", 0},
+		{"negative seconds", "-5", 0},
+		{"http date", now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{"http date in the past", now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"rfc850 date", now.Add(time.Minute).Format(time.RFC850), time.Minute},
+		{"garbage", "soon", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRetryAfter(tt.header, now); got != tt.want {
+				t.Errorf("parseRetryAfter(%q) = %s, want %s", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIErrorTemporary(t *testing.T) {
+	tests := []struct {
+		status int
+		want   bool
+	}{
+		{http.StatusTooManyRequests, true},
+		{http.StatusInternalServerError, true},
+		{http.StatusBadGateway, true},
+		{http.StatusServiceUnavailable, true},
+		{http.StatusGatewayTimeout, true},
+		{http.StatusBadRequest, false},
+		{http.StatusUnauthorized, false},
+		{http.StatusForbidden, false},
+		{http.StatusNotFound, false},
+	}
+	for _, tt := range tests {
+		if got := (&APIError{StatusCode: tt.status}).Temporary(); got != tt.want {
+			t.Errorf("APIError{%d}.Temporary() = %v, want %v", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestNewAPIError(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		retryAfter string
+		body       string
+		want       string
+		wantWait   time.Duration
+	}{
+		{
+			name:       "rate limited",
+			status:     http.StatusTooManyRequests,
+			retryAfter: "12",
+			body:       `{"status":429,"statusText":"Too Many Requests","errors":[{"message":"Rate limit exceeded","code":"rate_limited"}]}`,
+			want:       "beehiiv: 429 Too Many Requests: Rate limit exceeded",
+			wantWait:   12 * time.Second,
+		},
+		{
+			name:   "several errors",
+			status: http.StatusBadRequest,
+			body:   `{"errors":[{"message":"bad page"},{"message":""},{"message":"bad limit"}]}`,
+			want:   "beehiiv: 400 Bad Request: bad page; bad limit",
+		},
+		{
+			name:   "status text only",
+			status: http.StatusNotFound,
+			body:   `{"status":404,"statusText":"Publication not found"}`,
+			want:   "beehiiv: 404 Not Found: Publication not found",
+		},
+		{
+			name:   "not json",
+			status: http.StatusBadGateway,
+			body:   "<html>bad gateway</html>\n",
+			want:   "beehiiv: 502 Bad Gateway: <html>bad gateway</html>",
+		},
+		{
+			name:   "empty body",
+			status: http.StatusServiceUnavailable,
+			body:   `{}`,
+			want:   "beehiiv: 503 Service Unavailable",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+			if tt.retryAfter != "" {
+				resp.Header.Set("Retry-After", tt.retryAfter)
+			}
+			apiErr := newAPIError(resp, []byte(tt.body))
+			if apiErr.Error() != tt.want {
+				t.Errorf("Error() = %q, want %q", apiErr.Error(), tt.want)
+			}
+			if apiErr.RetryAfter != tt.wantWait {
+				t.Errorf("RetryAfter = %s, want %s", apiErr.RetryAfter, tt.wantWait)
+			}
+		})
+	}
+}
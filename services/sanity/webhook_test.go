@@ -0,0 +1,44 @@
+package sanity
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	const secret = "test-secret"
+	body := []byte(`{"_id":"post-1","_type":"blog"}`)
+	// Signed out of band: base64url(HMAC-SHA256(secret, "1700000000000." + body))
+	const header = "t=1700000000000,v1=lJoUv2oJUwj5bh6h3IwtUsb3PTX0ktHCkTXrTKLB5QE"
+	signedAt := time.UnixMilli(1700000000000)
+
+	tests := []struct {
+		name   string
+		header string
+		body   []byte
+		secret string
+		now    time.Time
+		want   error
+	}{
+		{"valid", header, body, secret, signedAt.Add(time.Second), nil},
+		{"valid with spaces", "t=1700000000000, v1=lJoUv2oJUwj5bh6h3IwtUsb3PTX0ktHCkTXrTKLB5QE", body, secret, signedAt, nil},
+		{"retried delivery", header, body, secret, signedAt.Add(2 * time.Hour), nil},
+		{"tampered body", header, []byte(`{"_id":"post-2","_type":"blog"}`), secret, signedAt, ErrInvalidSignature},
+		{"wrong secret", header, body, "other-secret", signedAt, ErrInvalidSignature},
+		{"tampered timestamp", "t=1700000000001,v1=lJoUv2oJUwj5bh6h3IwtUsb3PTX0ktHCkTXrTKLB5QE", body, secret, signedAt, ErrInvalidSignature},
+		{"expired", header, body, secret, signedAt.Add(webhookTolerance + time.Minute), ErrExpiredSignature},
+		{"from the future", header, body, secret, signedAt.Add(-webhookTolerance - time.Minute), ErrExpiredSignature},
+		{"missing header", "", body, secret, signedAt, ErrMissingSignature},
+		{"missing signature", "t=1700000000000", body, secret, signedAt, ErrMissingSignature},
+		{"malformed timestamp", "t=yesterday,v1=lJoUv2oJUwj5bh6h3IwtUsb3PTX0ktHCkTXrTKLB5QE", body, secret, signedAt, ErrMissingSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyWebhookSignature(tt.header, tt.body, tt.secret, tt.now)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("verifyWebhookSignature() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
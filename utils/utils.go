@@ -27,6 +27,10 @@ func GetDateRange(rangeType string) (string, string) {
 	}
 }
 
+// GetRangeTypes returns every supported range type, from shortest to longest
+func GetRangeTypes() []string {
+	return []string{"yesterday", "last7days", "last14days", "last30days", "last90days", "last180days", "last365days"}
+}
 
 func GetDaysFromRangeType(rangeType string) int {
 	switch rangeType {
@@ -100,6 +104,21 @@ func IsValidDateRange(startDate, endDate string) bool {
 	return start.Before(end) || start.Equal(end)
 }
 
+// GetPreviousPeriod returns the range of the same length that ends the day before startDate
+func GetPreviousPeriod(startDate, endDate string) (string, string, error) {
+	start, err := ParseDate(startDate)
+	if err != nil {
+		return "", "", err
+	}
+	end, err := ParseDate(endDate)
+	if err != nil {
+		return "", "", err
+	}
+	days := int(end.Sub(start).Hours()/24) + 1
+	prevEnd := start.AddDate(0, 0, -1)
+	return FormatDate(prevEnd.AddDate(0, 0, -(days - 1))), FormatDate(prevEnd), nil
+}
+
 // ContainsString checks if a string slice contains a specific string
 func ContainsString(slice []string, s string) bool {
 	for _, item := range slice {